@@ -8,30 +8,52 @@ import (
 	"io"
 	"log"
 	"os"
+	"sort"
+	"strconv"
+	"unicode/utf8"
 
 	"github.com/sirupsen/logrus"
 
+	"k8-api/operations"
+
 	v1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
 	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
 // setting a Global variable for the clientset so that I can resuse throughout the code
 var Kconfig *kubernetes.Clientset
 
+// Dynamic and Mapper reach any kind the cluster serves, CRDs included, through discovery
+var Dynamic dynamic.Interface
+var Mapper meta.ResettableRESTMapper
+
 // These are all the Structs that are used in the API later in this code
 type Pod struct {
-	Name            string
-	Status          string
-	CreatedAt       string
-	UniqueID        string
-	NodeName        string
-	IP              string
-	ContainersCount int
-	ContainersInfo  []Container
-	Labels          map[string]string
+	Name                    string
+	Status                  string
+	Phase                   string
+	Ready                   string
+	Restarts                int32
+	QOSClass                string
+	Owners                  []string
+	Conditions              []PodCondition
+	CreatedAt               string
+	UniqueID                string
+	NodeName                string
+	IP                      string
+	ContainersCount         int
+	ContainersInfo          []Container
+	InitContainersInfo      []Container
+	EphemeralContainersInfo []Container
+	Labels                  map[string]string
 }
 
 type Container struct {
@@ -40,23 +62,122 @@ type Container struct {
 	ImagePullPolicy string
 	Container       int
 	Port            []v1.ContainerPort
+	Ready           bool
+	Started         bool
+	RestartCount    int32
+	State           ContainerState
+	LastState       ContainerState
+	Requests        map[string]string
+	Limits          map[string]string
+	LivenessProbe   string
+	ReadinessProbe  string
+	StartupProbe    string
+}
+
+type ContainerState struct {
+	State      string
+	Reason     string
+	Message    string
+	ExitCode   int32
+	StartedAt  string
+	FinishedAt string
+}
+
+type PodCondition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
 }
 
 type Deployment struct {
-	Name      string
-	Status    string
-	CreatedAt string
-	UniqueID  string
-	Labels    map[string]string
+	Name                string
+	Status              string
+	Replicas            int32
+	ReadyReplicas       int32
+	UpdatedReplicas     int32
+	AvailableReplicas   int32
+	UnavailableReplicas int32
+	Conditions          []DeploymentCondition
+	Strategy            string
+	Images              map[string]string
+	Paused              bool
+	Generation          int64
+	ObservedGeneration  int64
+	ReplicaSets         []DeploymentReplicaSet
+	CreatedAt           string
+	UniqueID            string
+	Labels              map[string]string
+}
+
+type DeploymentCondition struct {
+	Type           string
+	Status         string
+	Reason         string
+	Message        string
+	LastUpdateTime string
+}
+
+type DeploymentReplicaSet struct {
+	Name          string
+	Revision      int64
+	ChangeCause   string
+	Replicas      int32
+	ReadyReplicas int32
+	Images        map[string]string
+	CreatedAt     string
 }
 
 type Configmap struct {
+	Name           string
+	Data           map[string]string
+	TruncatedKeys  []string
+	BinaryDataKeys []string
+	Size           int
+	Immutable      bool
+	ReferencedBy   []ConfigmapReference
+	CreatedAt      string
+	UniqueID       string
+	Labels         map[string]string
+}
+
+type ConfigmapReference struct {
+	Kind string
 	Name string
+	Via  []string
 }
 
 type Service struct {
-	Name  string
-	Ports string
+	Name                  string
+	Type                  string
+	ClusterIPs            []string
+	ExternalIPs           []string
+	LoadBalancerIngress   []string
+	ExternalName          string
+	Ports                 []ServicePort
+	Selector              map[string]string
+	SessionAffinity       string
+	ExternalTrafficPolicy string
+	EndpointSlices        []ServiceEndpointSlice
+	CreatedAt             string
+	UniqueID              string
+	Labels                map[string]string
+}
+
+type ServicePort struct {
+	Name       string
+	Protocol   string
+	Port       int32
+	TargetPort string
+	NodePort   int32
+}
+
+type ServiceEndpointSlice struct {
+	Name        string
+	AddressType string
+	Ports       []string
+	Ready       []Endpoint
+	NotReady    []Endpoint
 }
 
 type Secret struct {
@@ -75,10 +196,21 @@ type Replicationcontroller struct {
 }
 
 type Daemonset struct {
-	Name      string
-	CreatedAt string
-	UniqueID  string
-	Labels    map[string]string
+	Name                   string
+	DesiredNumberScheduled int32
+	CurrentNumberScheduled int32
+	NumberReady            int32
+	UpdatedNumberScheduled int32
+	NumberAvailable        int32
+	NumberMisscheduled     int32
+	UpdateStrategy         string
+	NodeSelector           map[string]string
+	Images                 map[string]string
+	Generation             int64
+	ObservedGeneration     int64
+	CreatedAt              string
+	UniqueID               string
+	Labels                 map[string]string
 }
 
 type Namespace struct {
@@ -90,11 +222,41 @@ type Namespace struct {
 type Event struct {
 	Name       string
 	Type       string
+	Reason     string
+	Message    string
+	ObjectKind string
 	ObjectName string
+	ObjectUID  string
+	Count      int32
+	FirstSeen  string
+	LastSeen   string
+	Source     string
+	Action     string
+	Series     *EventSeries
 	CreatedAt  string
 	UniqueID   string
 }
 
+type EventSeries struct {
+	Count            int32
+	LastObservedTime string
+}
+
+// EventQuery narrows down the events, empty fields match everything.
+// Since is a duration (1h) or an RFC3339 time, Order is newest (default) or oldest,
+// and API is core (default) or events.k8s.io for the events.k8s.io/v1 API.
+type EventQuery struct {
+	Kind   string
+	Name   string
+	UID    string
+	Type   string
+	Reason string
+	Since  string
+	Until  string
+	Order  string
+	API    string
+}
+
 //This function is used to interact with the Kubernetes Cluster to get the clienset
 // It has two options:
 // 1. Current it's setup to be used inside a cluster
@@ -126,6 +288,15 @@ func Main() {
 	// comment till here
 
 	Kconfig = clientset
+
+	Dynamic, err = dynamic.NewForConfig(config)
+	if err != nil {
+		logrus.Error(err.Error())
+	}
+	// Discovery is cached, the mapper is reset when it meets a resource it does not know yet.
+	// The shortcut expander turns short names like deploy or svc into the resource they stand for.
+	discovery := memory.NewMemCacheClient(clientset.Discovery())
+	Mapper = restmapper.NewShortcutExpander(restmapper.NewDeferredDiscoveryRESTMapper(discovery), discovery).(meta.ResettableRESTMapper)
 }
 
 // This function is used to get the list of all the pods in the cluster with container details
@@ -140,55 +311,21 @@ func Pods(AgentNamespace string, ContainerDetails bool, log *logrus.Entry) strin
 	}
 
 	var podInfo []Pod
-	var containerInfo []Container
 	pods, err := clientset.CoreV1().Pods(AgentNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("Recovered in Pods(): ", r)
-			}
-		}()
-		log.Panic("Unable to find pods. Error: " + err.Error())
-	} else {
-		for i := 0; i < len(pods.Items); i++ {
-
-			podInfo = append(podInfo,
-				Pod{
-					Name:            pods.Items[i].Name,
-					Status:          string(pods.Items[i].Status.Phase),
-					CreatedAt:       pods.Items[i].CreationTimestamp.String(),
-					UniqueID:        string(pods.Items[i].GetUID()),
-					NodeName:        string(pods.Items[i].Spec.NodeName),
-					IP:              string(pods.Items[i].Status.PodIP),
-					ContainersCount: len(pods.Items[i].Spec.Containers),
-					Labels:          pods.Items[i].Labels,
-				})
-			if ContainerDetails {
-
-				for j := 0; j < len(pods.Items[i].Spec.Containers); j++ {
-
-					containerInfo = append(containerInfo,
-						Container{
-							Name:            pods.Items[i].Spec.Containers[j].Name,
-							Container:       j,
-							Image:           pods.Items[i].Spec.Containers[j].Image,
-							ImagePullPolicy: string(pods.Items[i].Spec.Containers[j].ImagePullPolicy),
-							Port:            pods.Items[i].Spec.Containers[j].Ports,
-						})
-				}
-			}
-			podInfo[i].ContainersInfo = containerInfo
-		}
-
-		pods_json, err := json.Marshal(podInfo)
-		if err != nil {
-			log.Error(err.Error())
-		}
+		log.Error("Unable to find pods. Error: " + err.Error())
+		return err.Error()
+	}
+	for i := 0; i < len(pods.Items); i++ {
+		podInfo = append(podInfo, podDetails(&pods.Items[i], ContainerDetails))
+	}
 
-		return string(pods_json)
+	pods_json, err := json.Marshal(podInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
 	}
-	log.Error("Error in getting pods")
-	return "Error"
+	return string(pods_json)
 }
 
 // This function is used to get the list of all the logs in a pod.
@@ -222,45 +359,31 @@ func Deployments(AgentNamespace string, log *logrus.Entry) string {
 		AgentNamespace = "default"
 	}
 
-	//fmt.Printf("DEPLOYMENTS \n")
 	var deploymentInfo []Deployment
 	deployments, err := clientset.AppsV1().Deployments(AgentNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("Recovered in Deployments(): ", r)
-			}
-		}()
-		log.Panic("Unable to find Deployments. Error: " + err.Error())
-	} else {
-
-		for i := 0; i < len(deployments.Items); i++ {
-			//fmt.Println((deployments.Items[i].Status.Conditions))
-
-			deploymentInfo = append(deploymentInfo,
-				Deployment{
-					Name:      deployments.Items[i].Name,
-					Status:    string(deployments.Items[i].Status.Conditions[0].Type),
-					CreatedAt: deployments.Items[i].CreationTimestamp.String(),
-					UniqueID:  string(deployments.Items[i].UID),
-					Labels:    deployments.Items[i].Labels,
-				})
-		}
-
-		deployment_json, err := json.Marshal(deploymentInfo)
-		if err != nil {
-			log.Error(err.Error())
-			log.Fatal(err)
-		}
+		log.Error("Unable to find Deployments. Error: " + err.Error())
+		return err.Error()
+	}
+	replicaSets, err := replicaSetsByOwner(AgentNamespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	for i := 0; i < len(deployments.Items); i++ {
+		deploymentInfo = append(deploymentInfo, deploymentDetails(&deployments.Items[i], replicaSets[deployments.Items[i].UID]))
+	}
 
-		return string(deployment_json)
+	deployment_json, err := json.Marshal(deploymentInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
 	}
-	log.Error("Error in getting deployments")
-	return "Error"
+	return string(deployment_json)
 }
 
 // This function is used to get the list of all the Configmaps in the cluster
-func Configmaps(AgentNamespace string, log *logrus.Entry) string {
+func Configmaps(AgentNamespace string, valueLimit string, log *logrus.Entry) string {
 	clientset := Kconfig
 
 	if AgentNamespace == "" {
@@ -268,31 +391,67 @@ func Configmaps(AgentNamespace string, log *logrus.Entry) string {
 		log.Info("Namespace = default")
 		AgentNamespace = "default"
 	}
+	// Values longer than the limit are cut, so a big file in a ConfigMap does not blow up the list
+	limit := 1024
+	if valueLimit != "" {
+		n, err := strconv.Atoi(valueLimit)
+		if err != nil || n < 0 {
+			log.Error("Invalid valueLimit: " + valueLimit)
+			return "Invalid valueLimit: " + valueLimit
+		}
+		limit = n
+	}
 
 	var configmapsInfo []Configmap
 	configmaps, err := clientset.CoreV1().ConfigMaps(AgentNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("Recovered in Configmaps(): ", r)
+		log.Error("Unable to find Configmaps. Error: " + err.Error())
+		return err.Error()
+	}
+	references, err := configMapReferences(AgentNamespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	for i := 0; i < len(configmaps.Items); i++ {
+		configmap := configmaps.Items[i]
+		info := Configmap{
+			Name:         configmap.Name,
+			Data:         map[string]string{},
+			Immutable:    configmap.Immutable != nil && *configmap.Immutable,
+			ReferencedBy: references[configmap.Name],
+			CreatedAt:    configmap.CreationTimestamp.String(),
+			UniqueID:     string(configmap.UID),
+			Labels:       configmap.Labels,
+		}
+		for key, value := range configmap.Data {
+			info.Size += len(key) + len(value)
+			if limit > 0 && len(value) > limit {
+				// Cut on a character boundary, half a UTF-8 character would come out as U+FFFD
+				cut := limit
+				for cut > 0 && !utf8.RuneStart(value[cut]) {
+					cut--
+				}
+				value = value[:cut]
+				info.TruncatedKeys = append(info.TruncatedKeys, key)
 			}
-		}()
-		log.Panic("Unable to find Configmaps. Error: " + err.Error())
-	} else {
-		for i := 0; i < len(configmaps.Items); i++ {
-			configmapsInfo = append(configmapsInfo, Configmap{configmaps.Items[i].Name})
+			info.Data[key] = value
 		}
-
-		configmap_json, err := json.Marshal(configmapsInfo)
-		if err != nil {
-			log.Print(err.Error())
-			log.Fatal(err)
+		for key, value := range configmap.BinaryData {
+			info.Size += len(key) + len(value)
+			info.BinaryDataKeys = append(info.BinaryDataKeys, key)
 		}
+		sort.Strings(info.TruncatedKeys)
+		sort.Strings(info.BinaryDataKeys)
+		configmapsInfo = append(configmapsInfo, info)
+	}
 
-		return string(configmap_json)
+	configmap_json, err := json.Marshal(configmapsInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
 	}
-	log.Error("Error in getting configmaps")
-	return "Error"
+	return string(configmap_json)
 }
 
 // This function is used to get the list of all the Services in the cluster
@@ -308,71 +467,94 @@ func Services(AgentNamespace string, log *logrus.Entry) string {
 
 	services, err := clientset.CoreV1().Services(AgentNamespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("Recovered in Services()", r)
+		log.Error("Unable to find Services. Error: " + err.Error())
+		return err.Error()
+	}
+	slices, err := endpointSlicesByService(AgentNamespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	for i := 0; i < len(services.Items); i++ {
+		service := services.Items[i]
+		info := Service{
+			Name:                  service.Name,
+			Type:                  string(service.Spec.Type),
+			ClusterIPs:            service.Spec.ClusterIPs,
+			ExternalIPs:           service.Spec.ExternalIPs,
+			ExternalName:          service.Spec.ExternalName,
+			Selector:              service.Spec.Selector,
+			SessionAffinity:       string(service.Spec.SessionAffinity),
+			ExternalTrafficPolicy: string(service.Spec.ExternalTrafficPolicy),
+			EndpointSlices:        slices[service.Name],
+			CreatedAt:             service.CreationTimestamp.String(),
+			UniqueID:              string(service.UID),
+			Labels:                service.Labels,
+		}
+		for _, ingress := range service.Status.LoadBalancer.Ingress {
+			if ingress.IP != "" {
+				info.LoadBalancerIngress = append(info.LoadBalancerIngress, ingress.IP)
+			} else {
+				info.LoadBalancerIngress = append(info.LoadBalancerIngress, ingress.Hostname)
 			}
-		}()
-		log.Panic("Unable to find Services. Error: " + err.Error())
-	} else {
-		for i := 0; i < len(services.Items); i++ {
-			servicesInfo = append(servicesInfo, Service{Name: services.Items[i].Name, Ports: services.Items[i].Spec.Ports[0].TargetPort.String()})
 		}
-		service_json, err := json.Marshal(servicesInfo)
-		if err != nil {
-			log.Error(err.Error())
-			log.Fatal(err)
+		// Headless and ExternalName services can have no ports at all
+		for _, port := range service.Spec.Ports {
+			info.Ports = append(info.Ports, ServicePort{
+				Name:       port.Name,
+				Protocol:   string(port.Protocol),
+				Port:       port.Port,
+				TargetPort: port.TargetPort.String(),
+				NodePort:   port.NodePort,
+			})
 		}
-		//fmt.Println(string(pods_json))
-		return string(service_json)
+		servicesInfo = append(servicesInfo, info)
 	}
-	log.Error("Error in getting services")
-	return "Error"
+	service_json, err := json.Marshal(servicesInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(service_json)
 }
 
-// This function is used to get the list of all the events in the cluster
-func Events(AgentNamespace string, log *logrus.Entry) string {
+// This function maps every service in the namespace to its EndpointSlices, split in ready and not ready addresses
+func endpointSlicesByService(namespace string) (map[string][]ServiceEndpointSlice, error) {
 	clientset := Kconfig
-
-	var eventsInfo []Event
-	if AgentNamespace == "" {
-		log.Info("Namespace is empty")
-		log.Info("Namespace = default")
-		AgentNamespace = "default"
-	}
-	events, err := clientset.CoreV1().Events(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	slices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(context.Background(), metav1.ListOptions{})
 	if err != nil {
-		defer func() {
-			if r := recover(); r != nil {
-				log.Error("Recovered in Events()", r)
+		return nil, err
+	}
+	out := map[string][]ServiceEndpointSlice{}
+	for _, slice := range slices.Items {
+		service := slice.Labels[discoveryv1.LabelServiceName]
+		if service == "" {
+			continue
+		}
+		info := ServiceEndpointSlice{Name: slice.Name, AddressType: string(slice.AddressType)}
+		for _, port := range slice.Ports {
+			value := ""
+			if port.Name != nil && *port.Name != "" {
+				value = *port.Name + ":"
 			}
-		}()
-		log.Panic("Unable to find events. Error: " + err.Error())
-	} else {
-		for i := 0; i < len(events.Items); i++ {
-			eventsInfo = append(eventsInfo,
-				Event{
-					Name:       events.Items[i].Name,
-					ObjectName: (events.Items[i].InvolvedObject.Name),
-					CreatedAt:  events.Items[i].LastTimestamp.String(),
-					UniqueID:   string(events.Items[i].UID),
-					Type:       events.Items[i].Type,
-				})
+			if port.Port != nil {
+				value += strconv.Itoa(int(*port.Port))
+			}
+			if port.Protocol != nil {
+				value += "/" + string(*port.Protocol)
+			}
+			info.Ports = append(info.Ports, value)
 		}
-		event_json, err := json.Marshal(eventsInfo)
-		if err != nil {
-			defer func() {
-				if r := recover(); r != nil {
-					log.Error("Recovered in Events()", r)
-				}
-			}()
-			log.Panic("Issue in marshaling JSON in Events. Error: " + err.Error())
+		for _, endpoint := range sliceEndpoints(&slice) {
+			if endpoint.Ready {
+				info.Ready = append(info.Ready, endpoint)
+			} else {
+				info.NotReady = append(info.NotReady, endpoint)
+			}
 		}
-		//fmt.Println(string(pods_json))
-		return string(event_json)
+		out[service] = append(out[service], info)
 	}
-	log.Error("Error in getting events")
-	return "Error"
+	return out, nil
 }
 
 // This function is used to get the list of all the secrets in the cluster
@@ -474,29 +656,21 @@ func DaemonSet(AgentNamespace string, log *logrus.Entry) string {
 		log.Info("Namespace = default")
 		AgentNamespace = "default"
 	}
-	daemonsets, err := clientset.ExtensionsV1beta1().DaemonSets(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	daemonsets, err := clientset.AppsV1().DaemonSets(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find DaemonSets. Error: " + err.Error())
+		return err.Error()
+	}
+	var daemonsetInfo []Daemonset
+	for i := 0; i < len(daemonsets.Items); i++ {
+		daemonsetInfo = append(daemonsetInfo, daemonsetDetails(&daemonsets.Items[i]))
+	}
+	daemonset_json, err := json.Marshal(daemonsetInfo)
 	if err != nil {
 		log.Error(err.Error())
-	} else {
-		var daemonsetInfo []Daemonset
-		for i := 0; i < len(daemonsets.Items); i++ {
-			daemonsetInfo = append(daemonsetInfo,
-				Daemonset{
-					Name:      daemonsets.Items[i].Name,
-					CreatedAt: daemonsets.Items[i].CreationTimestamp.String(),
-					UniqueID:  string(daemonsets.Items[i].UID),
-					Labels:    (daemonsets.Items[i].Labels),
-				})
-		}
-		daemonset_json, err := json.Marshal(daemonsetInfo)
-		if err != nil {
-			log.Error(err.Error())
-		}
-		//fmt.Println(string(daemonset_json))
-		return string(daemonset_json)
+		return err.Error()
 	}
-	log.Error("Error in getting daemonsets")
-	return "Error"
+	return string(daemonset_json)
 }
 
 // This function is used to get the list of all the Namespaces in the cluster
@@ -627,7 +801,7 @@ func DeleteReplicationController(namespace string, replicationcontroller string,
 // This function Deletes the DaemonSet
 func DeleteDaemonSet(namespace string, daemonset string, log *logrus.Entry) string {
 	clientset := Kconfig
-	err := clientset.ExtensionsV1beta1().DaemonSets(namespace).Delete(context.Background(), daemonset, metav1.DeleteOptions{})
+	err := clientset.AppsV1().DaemonSets(namespace).Delete(context.Background(), daemonset, metav1.DeleteOptions{})
 	if err != nil {
 		log.Error(err.Error())
 		return err.Error()
@@ -660,99 +834,140 @@ func DeleteEvent(namespace string, event string, log *logrus.Entry) string {
 }
 
 // This function Deletes EVERYTHING in the namespace. My lil nuke!! MUWAHAHAHA
-func DeleteAll(namespace string, log *logrus.Entry) string {
+// It runs as an operation, so every kind is reported as a step and it stops between kinds once ctx is cancelled.
+// The claims of StatefulSets are only deleted when retainPVCs is false.
+func DeleteAll(ctx context.Context, namespace string, retainPVCs bool, log *logrus.Entry) string {
 	clientset := Kconfig
-	deployments, err := clientset.AppsV1().Deployments(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(deployments.Items); i++ {
-		err := clientset.AppsV1().Deployments(namespace).Delete(context.Background(), deployments.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
-		}
-	}
-	services, err := clientset.CoreV1().Services(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(services.Items); i++ {
-		err := clientset.CoreV1().Services(namespace).Delete(context.Background(), services.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
-		}
-	}
-	configmaps, err := clientset.CoreV1().ConfigMaps(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(configmaps.Items); i++ {
-		err := clientset.CoreV1().ConfigMaps(namespace).Delete(context.Background(), configmaps.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
-		}
-	}
-	secrets, err := clientset.CoreV1().Secrets(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(secrets.Items); i++ {
-		err := clientset.CoreV1().Secrets(namespace).Delete(context.Background(), secrets.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
-		}
-	}
-	replicationcontrollers, err := clientset.CoreV1().ReplicationControllers(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Print(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(replicationcontrollers.Items); i++ {
-		err := clientset.CoreV1().ReplicationControllers(namespace).Delete(context.Background(), replicationcontrollers.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
-		}
-	}
-	daemonsets, err := clientset.ExtensionsV1beta1().DaemonSets(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(daemonsets.Items); i++ {
-		err := clientset.ExtensionsV1beta1().DaemonSets(namespace).Delete(context.Background(), daemonsets.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
-		}
-	}
-	pods, err := clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
+	steps := []struct {
+		name string
+		run  func() (int, error)
+	}{
+		{"Deployments", func() (int, error) {
+			deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(deployments.Items); i++ {
+				err := clientset.AppsV1().Deployments(namespace).Delete(ctx, deployments.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(deployments.Items), nil
+		}},
+		{"StatefulSets", func() (int, error) {
+			statefulsets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(statefulsets.Items); i++ {
+				if err := deleteStatefulSet(ctx, &statefulsets.Items[i], retainPVCs, log); err != nil {
+					return i, err
+				}
+			}
+			return len(statefulsets.Items), nil
+		}},
+		{"Services", func() (int, error) {
+			services, err := clientset.CoreV1().Services(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(services.Items); i++ {
+				err := clientset.CoreV1().Services(namespace).Delete(ctx, services.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(services.Items), nil
+		}},
+		{"ConfigMaps", func() (int, error) {
+			configmaps, err := clientset.CoreV1().ConfigMaps(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(configmaps.Items); i++ {
+				err := clientset.CoreV1().ConfigMaps(namespace).Delete(ctx, configmaps.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(configmaps.Items), nil
+		}},
+		{"Secrets", func() (int, error) {
+			secrets, err := clientset.CoreV1().Secrets(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(secrets.Items); i++ {
+				err := clientset.CoreV1().Secrets(namespace).Delete(ctx, secrets.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(secrets.Items), nil
+		}},
+		{"ReplicationControllers", func() (int, error) {
+			replicationcontrollers, err := clientset.CoreV1().ReplicationControllers(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(replicationcontrollers.Items); i++ {
+				err := clientset.CoreV1().ReplicationControllers(namespace).Delete(ctx, replicationcontrollers.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(replicationcontrollers.Items), nil
+		}},
+		{"DaemonSets", func() (int, error) {
+			daemonsets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(daemonsets.Items); i++ {
+				err := clientset.AppsV1().DaemonSets(namespace).Delete(ctx, daemonsets.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(daemonsets.Items), nil
+		}},
+		{"Pods", func() (int, error) {
+			pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(pods.Items); i++ {
+				err := clientset.CoreV1().Pods(namespace).Delete(ctx, pods.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(pods.Items), nil
+		}},
+		{"Events", func() (int, error) {
+			events, err := clientset.CoreV1().Events(namespace).List(ctx, metav1.ListOptions{})
+			if err != nil {
+				return 0, err
+			}
+			for i := 0; i < len(events.Items); i++ {
+				err := clientset.CoreV1().Events(namespace).Delete(ctx, events.Items[i].Name, metav1.DeleteOptions{})
+				if err != nil {
+					return i, err
+				}
+			}
+			return len(events.Items), nil
+		}},
 	}
-	for i := 0; i < len(pods.Items); i++ {
-		err := clientset.CoreV1().Pods(namespace).Delete(context.Background(), pods.Items[i].Name, metav1.DeleteOptions{})
-		if err != nil {
-			log.Error(err.Error())
-			return err.Error()
+
+	for _, step := range steps {
+		if err := ctx.Err(); err != nil {
+			log.Warn("Delete All cancelled before " + step.name)
+			return "Delete All cancelled"
 		}
-	}
-	events, err := clientset.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
-	if err != nil {
-		log.Error(err.Error())
-		return err.Error()
-	}
-	for i := 0; i < len(events.Items); i++ {
-		err := clientset.CoreV1().Events(namespace).Delete(context.Background(), events.Items[i].Name, metav1.DeleteOptions{})
+		operations.Progress(ctx, "Deleting "+step.name)
+		deleted, err := step.run()
+		operations.Step(ctx, step.name, strconv.Itoa(deleted)+" deleted", err)
 		if err != nil {
 			log.Error(err.Error())
 			return err.Error()
@@ -0,0 +1,385 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	"github.com/sirupsen/logrus"
+
+	autoscalingv2 "k8s.io/api/autoscaling/v2"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// These are all the Structs that are used for HorizontalPodAutoscalers
+type Hpa struct {
+	Name            string
+	Target          string
+	MinReplicas     int32
+	MaxReplicas     int32
+	CurrentReplicas int32
+	DesiredReplicas int32
+	Metrics         []HpaMetric
+	Conditions      []HpaCondition
+	LastScaleTime   string
+	CreatedAt       string
+	UniqueID        string
+	Labels          map[string]string
+}
+
+type HpaMetric struct {
+	Type    string
+	Name    string
+	Target  string
+	Current string
+}
+
+type HpaCondition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+// HpaSpec is the simple JSON body accepted to create or update an autoscaler.
+// Utilizations are percentages of the pods' requests, on create CPU defaults to 80 when neither is set.
+type HpaSpec struct {
+	Name              string
+	Kind              string
+	Target            string
+	MinReplicas       int32
+	MaxReplicas       int32
+	CPUUtilization    int32
+	MemoryUtilization int32
+}
+
+// This function is used to get the list of all the HorizontalPodAutoscalers in the namespace
+func HPAs(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	hpas, err := clientset.AutoscalingV2().HorizontalPodAutoscalers(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find HorizontalPodAutoscalers. Error: " + err.Error())
+		return err.Error()
+	}
+	var hpaInfo []Hpa
+	for i := 0; i < len(hpas.Items); i++ {
+		hpa := hpas.Items[i]
+		info := Hpa{
+			Name:            hpa.Name,
+			Target:          hpa.Spec.ScaleTargetRef.Kind + "/" + hpa.Spec.ScaleTargetRef.Name,
+			MinReplicas:     1,
+			MaxReplicas:     hpa.Spec.MaxReplicas,
+			CurrentReplicas: hpa.Status.CurrentReplicas,
+			DesiredReplicas: hpa.Status.DesiredReplicas,
+			CreatedAt:       hpa.CreationTimestamp.String(),
+			UniqueID:        string(hpa.UID),
+			Labels:          hpa.Labels,
+		}
+		if hpa.Spec.MinReplicas != nil {
+			info.MinReplicas = *hpa.Spec.MinReplicas
+		}
+		if hpa.Status.LastScaleTime != nil {
+			info.LastScaleTime = hpa.Status.LastScaleTime.String()
+		}
+		for _, metric := range hpa.Spec.Metrics {
+			m := HpaMetric{Type: string(metric.Type), Name: metricName(metric), Target: metricTarget(metric)}
+			for _, current := range hpa.Status.CurrentMetrics {
+				if current.Type == metric.Type && metricStatusName(current) == m.Name {
+					m.Current = metricCurrent(current)
+					break
+				}
+			}
+			info.Metrics = append(info.Metrics, m)
+		}
+		for _, condition := range hpa.Status.Conditions {
+			info.Conditions = append(info.Conditions, HpaCondition{
+				Type:    string(condition.Type),
+				Status:  string(condition.Status),
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			})
+		}
+		hpaInfo = append(hpaInfo, info)
+	}
+	hpa_json, err := json.Marshal(hpaInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(hpa_json)
+}
+
+func metricName(metric autoscalingv2.MetricSpec) string {
+	switch {
+	case metric.Resource != nil:
+		return string(metric.Resource.Name)
+	case metric.ContainerResource != nil:
+		return metric.ContainerResource.Container + "/" + string(metric.ContainerResource.Name)
+	case metric.Pods != nil:
+		return metric.Pods.Metric.Name
+	case metric.Object != nil:
+		return metric.Object.DescribedObject.Kind + "/" + metric.Object.DescribedObject.Name + "/" + metric.Object.Metric.Name
+	case metric.External != nil:
+		return metric.External.Metric.Name
+	}
+	return ""
+}
+
+func metricStatusName(metric autoscalingv2.MetricStatus) string {
+	switch {
+	case metric.Resource != nil:
+		return string(metric.Resource.Name)
+	case metric.ContainerResource != nil:
+		return metric.ContainerResource.Container + "/" + string(metric.ContainerResource.Name)
+	case metric.Pods != nil:
+		return metric.Pods.Metric.Name
+	case metric.Object != nil:
+		return metric.Object.DescribedObject.Kind + "/" + metric.Object.DescribedObject.Name + "/" + metric.Object.Metric.Name
+	case metric.External != nil:
+		return metric.External.Metric.Name
+	}
+	return ""
+}
+
+func metricTarget(metric autoscalingv2.MetricSpec) string {
+	var target *autoscalingv2.MetricTarget
+	switch {
+	case metric.Resource != nil:
+		target = &metric.Resource.Target
+	case metric.ContainerResource != nil:
+		target = &metric.ContainerResource.Target
+	case metric.Pods != nil:
+		target = &metric.Pods.Target
+	case metric.Object != nil:
+		target = &metric.Object.Target
+	case metric.External != nil:
+		target = &metric.External.Target
+	default:
+		return ""
+	}
+	switch {
+	case target.AverageUtilization != nil:
+		return strconv.Itoa(int(*target.AverageUtilization)) + "%"
+	case target.AverageValue != nil:
+		return target.AverageValue.String()
+	case target.Value != nil:
+		return target.Value.String()
+	}
+	return ""
+}
+
+func metricCurrent(metric autoscalingv2.MetricStatus) string {
+	var current *autoscalingv2.MetricValueStatus
+	switch {
+	case metric.Resource != nil:
+		current = &metric.Resource.Current
+	case metric.ContainerResource != nil:
+		current = &metric.ContainerResource.Current
+	case metric.Pods != nil:
+		current = &metric.Pods.Current
+	case metric.Object != nil:
+		current = &metric.Object.Current
+	case metric.External != nil:
+		current = &metric.External.Current
+	default:
+		return ""
+	}
+	switch {
+	case current.AverageUtilization != nil:
+		return strconv.Itoa(int(*current.AverageUtilization)) + "%"
+	case current.AverageValue != nil:
+		return current.AverageValue.String()
+	case current.Value != nil:
+		return current.Value.String()
+	}
+	return ""
+}
+
+// This function checks the spec and turns it into the autoscaling/v2 spec
+func hpaSpec(namespace string, body []byte) (HpaSpec, autoscalingv2.HorizontalPodAutoscalerSpec, string) {
+	var spec HpaSpec
+	var out autoscalingv2.HorizontalPodAutoscalerSpec
+	if err := json.Unmarshal(body, &spec); err != nil {
+		return spec, out, "Invalid HPA spec. Error: " + err.Error()
+	}
+	if spec.Name == "" || spec.Target == "" {
+		return spec, out, "Name and Target are required"
+	}
+	if spec.MinReplicas == 0 {
+		spec.MinReplicas = 1
+	}
+	if spec.MinReplicas < 1 || spec.MaxReplicas < spec.MinReplicas {
+		return spec, out, "MaxReplicas must be at least MinReplicas, and MinReplicas at least 1"
+	}
+	if spec.CPUUtilization < 0 || spec.MemoryUtilization < 0 {
+		return spec, out, "Utilizations must be positive"
+	}
+	if spec.CPUUtilization == 0 && spec.MemoryUtilization == 0 {
+		spec.CPUUtilization = 80
+	}
+
+	target, problem := hpaTarget(namespace, spec.Kind, spec.Target)
+	if problem != "" {
+		return spec, out, problem
+	}
+	spec.Kind = target.Kind
+
+	out.ScaleTargetRef = target
+	out.MinReplicas = &spec.MinReplicas
+	out.MaxReplicas = spec.MaxReplicas
+	out.Metrics = utilizationMetrics(nil, spec)
+	return spec, out, ""
+}
+
+// The target has to exist, so a typo does not make an autoscaler that never does anything
+func hpaTarget(namespace string, kind string, name string) (autoscalingv2.CrossVersionObjectReference, string) {
+	var err error
+	switch kind {
+	case "Deployment", "":
+		kind = "Deployment"
+		_, err = Kconfig.AppsV1().Deployments(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	case "StatefulSet":
+		_, err = Kconfig.AppsV1().StatefulSets(namespace).Get(context.Background(), name, metav1.GetOptions{})
+	default:
+		return autoscalingv2.CrossVersionObjectReference{}, "Kind must be Deployment or StatefulSet"
+	}
+	if err != nil {
+		return autoscalingv2.CrossVersionObjectReference{}, err.Error()
+	}
+	return autoscalingv2.CrossVersionObjectReference{APIVersion: "apps/v1", Kind: kind, Name: name}, ""
+}
+
+// This function sets the CPU and memory utilization targets the spec gives on top of metrics. A metric of the same
+// resource is replaced, every other metric is kept as it is.
+func utilizationMetrics(metrics []autoscalingv2.MetricSpec, spec HpaSpec) []autoscalingv2.MetricSpec {
+	resources := []struct {
+		name        v1.ResourceName
+		utilization int32
+	}{{v1.ResourceCPU, spec.CPUUtilization}, {v1.ResourceMemory, spec.MemoryUtilization}}
+	for _, r := range resources {
+		if r.utilization == 0 {
+			continue
+		}
+		var kept []autoscalingv2.MetricSpec
+		for _, metric := range metrics {
+			if metric.Type != autoscalingv2.ResourceMetricSourceType || metric.Resource == nil || metric.Resource.Name != r.name {
+				kept = append(kept, metric)
+			}
+		}
+		u := r.utilization
+		metrics = append(kept, autoscalingv2.MetricSpec{
+			Type: autoscalingv2.ResourceMetricSourceType,
+			Resource: &autoscalingv2.ResourceMetricSource{
+				Name:   r.name,
+				Target: autoscalingv2.MetricTarget{Type: autoscalingv2.UtilizationMetricType, AverageUtilization: &u},
+			},
+		})
+	}
+	return metrics
+}
+
+// This function creates a HorizontalPodAutoscaler for a Deployment or StatefulSet from a HpaSpec
+func CreateHPA(namespace string, body []byte, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	spec, hpaspec, problem := hpaSpec(namespace, body)
+	if problem != "" {
+		log.Error(problem)
+		return problem
+	}
+	hpa := &autoscalingv2.HorizontalPodAutoscaler{
+		ObjectMeta: metav1.ObjectMeta{Name: spec.Name, Namespace: namespace},
+		Spec:       hpaspec,
+	}
+	_, err := Kconfig.AutoscalingV2().HorizontalPodAutoscalers(namespace).Create(context.Background(), hpa, metav1.CreateOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("HorizontalPodAutoscaler: " + spec.Name + " Created!")
+	return "HorizontalPodAutoscaler: " + spec.Name + " Created!"
+}
+
+// This function changes an existing HorizontalPodAutoscaler with the fields set in a HpaSpec, found by Name.
+// Fields left out keep their value, and the metrics the simple spec can not express (pods, object, external,
+// container resource, or resources other than CPU and memory) are kept as they are, as is the behavior.
+func UpdateHPA(namespace string, body []byte, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	var spec HpaSpec
+	if err := json.Unmarshal(body, &spec); err != nil {
+		log.Error("Invalid HPA spec. Error: " + err.Error())
+		return "Invalid HPA spec. Error: " + err.Error()
+	}
+	if spec.Name == "" {
+		log.Error("Name is required")
+		return "Name is required"
+	}
+	if spec.MinReplicas < 0 || spec.MaxReplicas < 0 || spec.CPUUtilization < 0 || spec.MemoryUtilization < 0 {
+		log.Error("Replicas and utilizations must be positive")
+		return "Replicas and utilizations must be positive"
+	}
+	hpa, err := Kconfig.AutoscalingV2().HorizontalPodAutoscalers(namespace).Get(context.Background(), spec.Name, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+
+	if spec.Target != "" || spec.Kind != "" {
+		if spec.Target == "" {
+			spec.Target = hpa.Spec.ScaleTargetRef.Name
+		}
+		target, problem := hpaTarget(namespace, spec.Kind, spec.Target)
+		if problem != "" {
+			log.Error(problem)
+			return problem
+		}
+		hpa.Spec.ScaleTargetRef = target
+	}
+	if spec.MinReplicas != 0 {
+		hpa.Spec.MinReplicas = &spec.MinReplicas
+	}
+	if spec.MaxReplicas != 0 {
+		hpa.Spec.MaxReplicas = spec.MaxReplicas
+	}
+	minReplicas := int32(1)
+	if hpa.Spec.MinReplicas != nil {
+		minReplicas = *hpa.Spec.MinReplicas
+	}
+	if minReplicas < 1 || hpa.Spec.MaxReplicas < minReplicas {
+		log.Error("MaxReplicas must be at least MinReplicas, and MinReplicas at least 1")
+		return "MaxReplicas must be at least MinReplicas, and MinReplicas at least 1"
+	}
+	hpa.Spec.Metrics = utilizationMetrics(hpa.Spec.Metrics, spec)
+
+	_, err = Kconfig.AutoscalingV2().HorizontalPodAutoscalers(namespace).Update(context.Background(), hpa, metav1.UpdateOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("HorizontalPodAutoscaler: " + spec.Name + " Updated!")
+	return "HorizontalPodAutoscaler: " + spec.Name + " Updated!"
+}
+
+// This function Deletes the HorizontalPodAutoscaler
+func DeleteHPA(namespace string, hpa string, log *logrus.Entry) string {
+	err := Kconfig.AutoscalingV2().HorizontalPodAutoscalers(namespace).Delete(context.Background(), hpa, metav1.DeleteOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("HorizontalPodAutoscaler: " + hpa + " Deleted!")
+	return "HorizontalPodAutoscaler: " + hpa + " Deleted!"
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"unicode/utf8"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// This function maps every ConfigMap in the namespace to the workloads and pods using it through a volume,
+// envFrom or an env valueFrom. Workloads managed by another one (the ReplicaSets of a Deployment, the Jobs
+// of a CronJob) are left out, their owner is listed already.
+func configMapReferences(namespace string) (map[string][]ConfigmapReference, error) {
+	clientset := Kconfig
+	ctx := context.Background()
+	out := map[string][]ConfigmapReference{}
+	add := func(kind string, obj metav1.Object, spec *v1.PodSpec) {
+		for configmap, via := range podSpecConfigMaps(spec) {
+			out[configmap] = append(out[configmap], ConfigmapReference{Kind: kind, Name: obj.GetName(), Via: via})
+		}
+	}
+
+	deployments, err := clientset.AppsV1().Deployments(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range deployments.Items {
+		add("Deployment", &deployments.Items[i], &deployments.Items[i].Spec.Template.Spec)
+	}
+	statefulsets, err := clientset.AppsV1().StatefulSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range statefulsets.Items {
+		add("StatefulSet", &statefulsets.Items[i], &statefulsets.Items[i].Spec.Template.Spec)
+	}
+	daemonsets, err := clientset.AppsV1().DaemonSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range daemonsets.Items {
+		add("DaemonSet", &daemonsets.Items[i], &daemonsets.Items[i].Spec.Template.Spec)
+	}
+	replicasets, err := clientset.AppsV1().ReplicaSets(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range replicasets.Items {
+		if metav1.GetControllerOf(&replicasets.Items[i]) == nil {
+			add("ReplicaSet", &replicasets.Items[i], &replicasets.Items[i].Spec.Template.Spec)
+		}
+	}
+	cronjobs, err := clientset.BatchV1().CronJobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range cronjobs.Items {
+		add("CronJob", &cronjobs.Items[i], &cronjobs.Items[i].Spec.JobTemplate.Spec.Template.Spec)
+	}
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range jobs.Items {
+		if metav1.GetControllerOf(&jobs.Items[i]) == nil {
+			add("Job", &jobs.Items[i], &jobs.Items[i].Spec.Template.Spec)
+		}
+	}
+	// Pods are listed whatever owns them, they are what breaks when the ConfigMap goes
+	pods, err := clientset.CoreV1().Pods(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for i := range pods.Items {
+		add("Pod", &pods.Items[i], &pods.Items[i].Spec)
+	}
+	return out, nil
+}
+
+// This function lists the ConfigMaps a pod spec uses and how, e.g. "volume config" or "env app/LOG_LEVEL"
+func podSpecConfigMaps(spec *v1.PodSpec) map[string][]string {
+	refs := map[string][]string{}
+	for _, volume := range spec.Volumes {
+		if volume.ConfigMap != nil {
+			refs[volume.ConfigMap.Name] = append(refs[volume.ConfigMap.Name], "volume "+volume.Name)
+		}
+		if volume.Projected != nil {
+			for _, source := range volume.Projected.Sources {
+				if source.ConfigMap != nil {
+					refs[source.ConfigMap.Name] = append(refs[source.ConfigMap.Name], "volume "+volume.Name)
+				}
+			}
+		}
+	}
+	containers := append(append([]v1.Container{}, spec.InitContainers...), spec.Containers...)
+	for _, ec := range spec.EphemeralContainers {
+		containers = append(containers, v1.Container(ec.EphemeralContainerCommon))
+	}
+	for _, container := range containers {
+		for _, from := range container.EnvFrom {
+			if from.ConfigMapRef != nil {
+				refs[from.ConfigMapRef.Name] = append(refs[from.ConfigMapRef.Name], "envFrom "+container.Name)
+			}
+		}
+		for _, env := range container.Env {
+			if env.ValueFrom != nil && env.ValueFrom.ConfigMapKeyRef != nil {
+				name := env.ValueFrom.ConfigMapKeyRef.Name
+				refs[name] = append(refs[name], "env "+container.Name+"/"+env.Name)
+			}
+		}
+	}
+	return refs
+}
+
+// This function creates a ConfigMap from the keys of the request. Values that are not UTF-8 go to binaryData.
+func CreateConfigMap(namespace string, name string, request KeyValueRequest, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	if err := request.check(false); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	configmap := &v1.ConfigMap{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}}
+	setConfigMapKeys(configmap, request)
+	configmap, err := Kconfig.CoreV1().ConfigMaps(namespace).Create(context.Background(), configmap, metav1.CreateOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("ConfigMap: " + name + " Created!")
+	return configMapResult(configmap, log)
+}
+
+// This function sets and removes keys of a ConfigMap, the other keys are left as they are.
+// With a resourceVersion the update only goes through if nobody changed the ConfigMap since that version.
+func UpdateConfigMap(namespace string, name string, request KeyValueRequest, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	if err := request.check(true); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	client := Kconfig.CoreV1().ConfigMaps(namespace)
+	configmap, err := client.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	for _, key := range request.Remove {
+		_, inData := configmap.Data[key]
+		_, inBinaryData := configmap.BinaryData[key]
+		if !inData && !inBinaryData {
+			log.Error("ConfigMap: " + name + " has no key " + key)
+			return "ConfigMap: " + name + " has no key " + key
+		}
+	}
+	if request.ResourceVersion != "" {
+		configmap.ResourceVersion = request.ResourceVersion
+	}
+	setConfigMapKeys(configmap, request)
+	configmap, err = client.Update(context.Background(), configmap, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		log.Error("ConfigMap: " + name + " was changed since it was read, get it again and retry. Error: " + err.Error())
+		return "ConfigMap: " + name + " was changed since it was read, get it again and retry. Error: " + err.Error()
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("ConfigMap: " + name + " Updated!")
+	return configMapResult(configmap, log)
+}
+
+func setConfigMapKeys(configmap *v1.ConfigMap, request KeyValueRequest) {
+	for _, key := range request.Remove {
+		delete(configmap.Data, key)
+		delete(configmap.BinaryData, key)
+	}
+	for key, value := range request.Data {
+		delete(configmap.Data, key)
+		delete(configmap.BinaryData, key)
+		if utf8.Valid(value) {
+			if configmap.Data == nil {
+				configmap.Data = map[string]string{}
+			}
+			configmap.Data[key] = string(value)
+		} else {
+			if configmap.BinaryData == nil {
+				configmap.BinaryData = map[string][]byte{}
+			}
+			configmap.BinaryData[key] = value
+		}
+	}
+}
+
+func configMapResult(configmap *v1.ConfigMap, log *logrus.Entry) string {
+	keys := map[string][]byte{}
+	for key := range configmap.Data {
+		keys[key] = nil
+	}
+	result := KeyValueResult{
+		Kind:            "ConfigMap",
+		Name:            configmap.Name,
+		ResourceVersion: configmap.ResourceVersion,
+		Keys:            sortedKeys(keys, configmap.BinaryData),
+	}
+	result_json, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(result_json)
+}
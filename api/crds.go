@@ -0,0 +1,216 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/client-go/util/jsonpath"
+)
+
+var crdResource = schema.GroupVersionResource{Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"}
+
+// These are all the Structs that are used for CustomResourceDefinitions
+type Crd struct {
+	Name          string
+	Group         string
+	Kind          string
+	Plural        string
+	ShortNames    []string
+	Scope         string
+	Versions      []CrdVersion
+	Established   bool
+	NamesAccepted bool
+	CreatedAt     string
+	UniqueID      string
+	Labels        map[string]string
+}
+
+type CrdVersion struct {
+	Name       string
+	Served     bool
+	Storage    bool
+	Deprecated bool
+}
+
+// CustomResourceTable is the list of a CRD's objects laid out like kubectl get shows it
+type CustomResourceTable struct {
+	Kind    string
+	Version string
+	Columns []string
+	Rows    []CustomResourceRow
+}
+
+type CustomResourceRow struct {
+	Name      string
+	Namespace string
+	Cells     []string
+}
+
+// The CRDs come through the dynamic client and are converted, so no extra clientset is needed for them
+func getCRD(name string) (*apiextensionsv1.CustomResourceDefinition, error) {
+	obj, err := Dynamic.Resource(crdResource).Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
+	}
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	err = runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, crd)
+	return crd, err
+}
+
+// This function is used to get the list of all the CustomResourceDefinitions in the cluster
+func CRDs(log *logrus.Entry) string {
+	list, err := Dynamic.Resource(crdResource).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find CustomResourceDefinitions. Error: " + err.Error())
+		return err.Error()
+	}
+	var crdInfo []Crd
+	for i := 0; i < len(list.Items); i++ {
+		crd := apiextensionsv1.CustomResourceDefinition{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(list.Items[i].Object, &crd); err != nil {
+			log.Error(err.Error())
+			return err.Error()
+		}
+		info := Crd{
+			Name:       crd.Name,
+			Group:      crd.Spec.Group,
+			Kind:       crd.Spec.Names.Kind,
+			Plural:     crd.Spec.Names.Plural,
+			ShortNames: crd.Spec.Names.ShortNames,
+			Scope:      string(crd.Spec.Scope),
+			CreatedAt:  crd.CreationTimestamp.String(),
+			UniqueID:   string(crd.UID),
+			Labels:     crd.Labels,
+		}
+		for _, version := range crd.Spec.Versions {
+			info.Versions = append(info.Versions, CrdVersion{
+				Name:       version.Name,
+				Served:     version.Served,
+				Storage:    version.Storage,
+				Deprecated: version.Deprecated,
+			})
+		}
+		for _, condition := range crd.Status.Conditions {
+			switch condition.Type {
+			case apiextensionsv1.Established:
+				info.Established = condition.Status == apiextensionsv1.ConditionTrue
+			case apiextensionsv1.NamesAccepted:
+				info.NamesAccepted = condition.Status == apiextensionsv1.ConditionTrue
+			}
+		}
+		crdInfo = append(crdInfo, info)
+	}
+	crd_json, err := json.Marshal(crdInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(crd_json)
+}
+
+// This function lists the objects of a CRD as a table of its additionalPrinterColumns, the way kubectl get does.
+// version defaults to the storage version, and wide also shows the columns with a priority above 0.
+func CustomResources(crdName string, version string, AgentNamespace string, allNamespaces bool, wide bool, log *logrus.Entry) string {
+	crd, err := getCRD(crdName)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var crdVersion *apiextensionsv1.CustomResourceDefinitionVersion
+	for i := range crd.Spec.Versions {
+		v := &crd.Spec.Versions[i]
+		if (version == "" && v.Storage) || v.Name == version {
+			crdVersion = v
+			break
+		}
+	}
+	if crdVersion == nil || !crdVersion.Served {
+		log.Error("CustomResourceDefinition: " + crdName + " does not serve version " + version)
+		return "CustomResourceDefinition: " + crdName + " does not serve version " + version
+	}
+
+	// Every printer column is parsed once before the objects are listed
+	type column struct {
+		name     string
+		kind     string
+		template *jsonpath.JSONPath
+	}
+	var columns []column
+	for _, printer := range crdVersion.AdditionalPrinterColumns {
+		if printer.Priority > 0 && !wide {
+			continue
+		}
+		template := jsonpath.New(printer.Name).AllowMissingKeys(true)
+		if err := template.Parse("{" + printer.JSONPath + "}"); err != nil {
+			log.Warn("Printer column " + printer.Name + " has an invalid JSONPath: " + err.Error())
+			continue
+		}
+		columns = append(columns, column{name: printer.Name, kind: printer.Type, template: template})
+	}
+	// Without printer columns kubectl only shows the age
+	if len(crdVersion.AdditionalPrinterColumns) == 0 {
+		template := jsonpath.New("Age")
+		_ = template.Parse("{.metadata.creationTimestamp}")
+		columns = append(columns, column{name: "Age", kind: "date", template: template})
+	}
+
+	gvr := schema.GroupVersionResource{Group: crd.Spec.Group, Version: crdVersion.Name, Resource: crd.Spec.Names.Plural}
+	var list *unstructured.UnstructuredList
+	if crd.Spec.Scope == apiextensionsv1.ClusterScoped || allNamespaces {
+		list, err = Dynamic.Resource(gvr).List(context.Background(), metav1.ListOptions{})
+	} else {
+		if AgentNamespace == "" {
+			log.Info("Namespace is empty")
+			log.Info("Namespace = default")
+			AgentNamespace = "default"
+		}
+		list, err = Dynamic.Resource(gvr).Namespace(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	}
+	if err != nil {
+		log.Error("Unable to find " + crd.Spec.Names.Plural + ". Error: " + err.Error())
+		return err.Error()
+	}
+
+	table := CustomResourceTable{Kind: crd.Spec.Names.Kind, Version: crdVersion.Name}
+	for _, c := range columns {
+		table.Columns = append(table.Columns, c.name)
+	}
+	for _, obj := range list.Items {
+		row := CustomResourceRow{Name: obj.GetName(), Namespace: obj.GetNamespace()}
+		for _, c := range columns {
+			row.Cells = append(row.Cells, printerCell(c.template, c.kind, obj.Object))
+		}
+		table.Rows = append(table.Rows, row)
+	}
+	table_json, err := json.Marshal(table)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(table_json)
+}
+
+// A date column shows the age, like kubectl does; a value that cannot be read is left empty
+func printerCell(template *jsonpath.JSONPath, kind string, obj map[string]interface{}) string {
+	var out bytes.Buffer
+	if err := template.Execute(&out, obj); err != nil {
+		return ""
+	}
+	value := out.String()
+	if kind == "date" && value != "" {
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return duration.HumanDuration(time.Since(t))
+		}
+	}
+	return value
+}
@@ -0,0 +1,99 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// RolloutStatus tells whether the latest spec of a workload is rolled out, in the words kubectl rollout status uses
+type RolloutStatus struct {
+	Done    bool
+	Message string
+}
+
+// The same annotation kubectl rollout restart sets, changing it makes the controller replace every pod
+const restartedAtAnnotation = "kubectl.kubernetes.io/restartedAt"
+
+func restartPatch() []byte {
+	return []byte(fmt.Sprintf(`{"spec":{"template":{"metadata":{"annotations":{%q:%q}}}}}`, restartedAtAnnotation, time.Now().Format(time.RFC3339)))
+}
+
+func daemonsetDetails(daemonset *appsv1.DaemonSet) Daemonset {
+	info := Daemonset{
+		Name:                   daemonset.Name,
+		DesiredNumberScheduled: daemonset.Status.DesiredNumberScheduled,
+		CurrentNumberScheduled: daemonset.Status.CurrentNumberScheduled,
+		NumberReady:            daemonset.Status.NumberReady,
+		UpdatedNumberScheduled: daemonset.Status.UpdatedNumberScheduled,
+		NumberAvailable:        daemonset.Status.NumberAvailable,
+		NumberMisscheduled:     daemonset.Status.NumberMisscheduled,
+		UpdateStrategy:         string(daemonset.Spec.UpdateStrategy.Type),
+		NodeSelector:           daemonset.Spec.Template.Spec.NodeSelector,
+		Images:                 containerImages(&daemonset.Spec.Template.Spec),
+		Generation:             daemonset.Generation,
+		ObservedGeneration:     daemonset.Status.ObservedGeneration,
+		CreatedAt:              daemonset.CreationTimestamp.String(),
+		UniqueID:               string(daemonset.UID),
+		Labels:                 daemonset.Labels,
+	}
+	if ru := daemonset.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.MaxUnavailable != nil {
+		info.UpdateStrategy += " (maxUnavailable " + ru.MaxUnavailable.String()
+		if ru.MaxSurge != nil {
+			info.UpdateStrategy += ", maxSurge " + ru.MaxSurge.String()
+		}
+		info.UpdateStrategy += ")"
+	}
+	return info
+}
+
+// This function restarts every pod of the DaemonSet, one node at a time as its update strategy allows
+func RestartDaemonSet(namespace string, daemonset string, log *logrus.Entry) string {
+	_, err := Kconfig.AppsV1().DaemonSets(namespace).Patch(context.Background(), daemonset, types.StrategicMergePatchType, restartPatch(), metav1.PatchOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("DaemonSet: " + daemonset + " Restarted!")
+	return "DaemonSet: " + daemonset + " Restarted!"
+}
+
+// This function tells whether the DaemonSet has rolled out its latest spec to every node
+func DaemonSetRolloutStatus(namespace string, daemonset string, log *logrus.Entry) string {
+	ds, err := Kconfig.AppsV1().DaemonSets(namespace).Get(context.Background(), daemonset, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	status_json, err := json.Marshal(daemonsetRolloutStatus(ds))
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(status_json)
+}
+
+func daemonsetRolloutStatus(ds *appsv1.DaemonSet) RolloutStatus {
+	if ds.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		return RolloutStatus{Done: true, Message: "Rollout status is only available for the RollingUpdate strategy"}
+	}
+	if ds.Generation > ds.Status.ObservedGeneration {
+		return RolloutStatus{Message: "Waiting for daemon set spec update to be observed"}
+	}
+	desired := strconv.Itoa(int(ds.Status.DesiredNumberScheduled))
+	if ds.Status.UpdatedNumberScheduled < ds.Status.DesiredNumberScheduled {
+		return RolloutStatus{Message: "Waiting for daemon set " + ds.Name + " rollout to finish: " + strconv.Itoa(int(ds.Status.UpdatedNumberScheduled)) + " out of " + desired + " new pods have been updated"}
+	}
+	if ds.Status.NumberAvailable < ds.Status.DesiredNumberScheduled {
+		return RolloutStatus{Message: "Waiting for daemon set " + ds.Name + " rollout to finish: " + strconv.Itoa(int(ds.Status.NumberAvailable)) + " of " + desired + " updated pods are available"}
+	}
+	return RolloutStatus{Done: true, Message: "Daemon set " + ds.Name + " successfully rolled out"}
+}
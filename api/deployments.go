@@ -0,0 +1,148 @@
+package api
+
+import (
+	"context"
+	"sort"
+	"strconv"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// These are the annotations the deployment controller and kubectl put on ReplicaSets
+const (
+	revisionAnnotation    = "deployment.kubernetes.io/revision"
+	changeCauseAnnotation = "kubernetes.io/change-cause"
+)
+
+// This function maps every Deployment in the namespace, by UID, to the ReplicaSets it controls, newest revision first
+func replicaSetsByOwner(namespace string) (map[types.UID][]appsv1.ReplicaSet, error) {
+	replicaSets, err := Kconfig.AppsV1().ReplicaSets(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	out := map[types.UID][]appsv1.ReplicaSet{}
+	for _, rs := range replicaSets.Items {
+		if owner := metav1.GetControllerOf(&rs); owner != nil && owner.Kind == "Deployment" {
+			out[owner.UID] = append(out[owner.UID], rs)
+		}
+	}
+	for uid := range out {
+		owned := out[uid]
+		sort.Slice(owned, func(i, j int) bool { return replicaSetRevision(&owned[i]) > replicaSetRevision(&owned[j]) })
+	}
+	return out, nil
+}
+
+func replicaSetRevision(rs *appsv1.ReplicaSet) int64 {
+	revision, _ := strconv.ParseInt(rs.Annotations[revisionAnnotation], 10, 64)
+	return revision
+}
+
+func containerImages(spec *v1.PodSpec) map[string]string {
+	images := map[string]string{}
+	for _, container := range spec.Containers {
+		images[container.Name] = container.Image
+	}
+	return images
+}
+
+func deploymentDetails(deployment *appsv1.Deployment, replicaSets []appsv1.ReplicaSet) Deployment {
+	info := Deployment{
+		Name:                deployment.Name,
+		Replicas:            1,
+		ReadyReplicas:       deployment.Status.ReadyReplicas,
+		UpdatedReplicas:     deployment.Status.UpdatedReplicas,
+		AvailableReplicas:   deployment.Status.AvailableReplicas,
+		UnavailableReplicas: deployment.Status.UnavailableReplicas,
+		Strategy:            string(deployment.Spec.Strategy.Type),
+		Images:              containerImages(&deployment.Spec.Template.Spec),
+		Paused:              deployment.Spec.Paused,
+		Generation:          deployment.Generation,
+		ObservedGeneration:  deployment.Status.ObservedGeneration,
+		CreatedAt:           deployment.CreationTimestamp.String(),
+		UniqueID:            string(deployment.UID),
+		Labels:              deployment.Labels,
+	}
+	if deployment.Spec.Replicas != nil {
+		info.Replicas = *deployment.Spec.Replicas
+	}
+	if ru := deployment.Spec.Strategy.RollingUpdate; ru != nil {
+		if ru.MaxSurge != nil && ru.MaxUnavailable != nil {
+			info.Strategy += " (maxSurge " + ru.MaxSurge.String() + ", maxUnavailable " + ru.MaxUnavailable.String() + ")"
+		}
+	}
+	for _, condition := range deployment.Status.Conditions {
+		info.Conditions = append(info.Conditions, DeploymentCondition{
+			Type:           string(condition.Type),
+			Status:         string(condition.Status),
+			Reason:         condition.Reason,
+			Message:        condition.Message,
+			LastUpdateTime: condition.LastUpdateTime.String(),
+		})
+	}
+	info.Status = deploymentStatus(deployment)
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		replicas := int32(0)
+		if rs.Spec.Replicas != nil {
+			replicas = *rs.Spec.Replicas
+		}
+		info.ReplicaSets = append(info.ReplicaSets, DeploymentReplicaSet{
+			Name:          rs.Name,
+			Revision:      replicaSetRevision(rs),
+			ChangeCause:   rs.Annotations[changeCauseAnnotation],
+			Replicas:      replicas,
+			ReadyReplicas: rs.Status.ReadyReplicas,
+			Images:        containerImages(&rs.Spec.Template.Spec),
+			CreatedAt:     rs.CreationTimestamp.String(),
+		})
+	}
+	return info
+}
+
+// This function sums up the Deployment in one word, the way kubectl rollout status reads the conditions.
+// A deployment that has no conditions yet is Pending.
+func deploymentStatus(deployment *appsv1.Deployment) string {
+	if deployment.Spec.Paused {
+		return "Paused"
+	}
+	var available, progressing *appsv1.DeploymentCondition
+	for i := range deployment.Status.Conditions {
+		condition := &deployment.Status.Conditions[i]
+		switch condition.Type {
+		case appsv1.DeploymentReplicaFailure:
+			if condition.Status == v1.ConditionTrue {
+				return "ReplicaFailure"
+			}
+		case appsv1.DeploymentAvailable:
+			available = condition
+		case appsv1.DeploymentProgressing:
+			progressing = condition
+		}
+	}
+	// The counts are compared with the replicas asked for, the status ones still include the pods of a surge
+	desired := int32(1)
+	if deployment.Spec.Replicas != nil {
+		desired = *deployment.Spec.Replicas
+	}
+	switch {
+	case progressing != nil && progressing.Reason == "ProgressDeadlineExceeded":
+		return "Stalled"
+	case deployment.Status.ObservedGeneration < deployment.Generation,
+		deployment.Status.UpdatedReplicas < desired,
+		deployment.Status.Replicas > deployment.Status.UpdatedReplicas:
+		return "Progressing"
+	case available != nil && available.Status == v1.ConditionTrue &&
+		deployment.Status.ReadyReplicas >= desired && deployment.Status.AvailableReplicas >= desired:
+		return "Available"
+	case available != nil && available.Status == v1.ConditionTrue:
+		// Enough pods for the Available condition, but fewer than asked for
+		return "Degraded"
+	case available != nil || progressing != nil:
+		return "Unavailable"
+	}
+	return "Pending"
+}
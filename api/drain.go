@@ -0,0 +1,184 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8-api/operations"
+
+	v1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// This function marks a Node unschedulable (cordon) or schedulable again (uncordon)
+func CordonNode(node string, cordon bool, log *logrus.Entry) string {
+	if err := setUnschedulable(context.Background(), node, cordon); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if cordon {
+		log.Info("Node: " + node + " Cordoned!")
+		return "Node: " + node + " Cordoned!"
+	}
+	log.Info("Node: " + node + " Uncordoned!")
+	return "Node: " + node + " Uncordoned!"
+}
+
+func setUnschedulable(ctx context.Context, node string, unschedulable bool) error {
+	patch := []byte(fmt.Sprintf(`{"spec":{"unschedulable":%t}}`, unschedulable))
+	_, err := Kconfig.CoreV1().Nodes().Patch(ctx, node, types.MergePatchType, patch, metav1.PatchOptions{})
+	return err
+}
+
+// This function drains a Node: it cordons it and evicts its pods through the Eviction API, so PodDisruptionBudgets
+// are respected. DaemonSet and mirror pods are skipped, and pods without a controller are only evicted with force.
+// gracePeriod is in seconds (-1 keeps each pod's own), and the drain gives up after timeout.
+// It runs as an operation, so every pod is reported as a step.
+func DrainNode(ctx context.Context, node string, gracePeriod string, timeout string, force bool, log *logrus.Entry) string {
+	clientset := Kconfig
+	grace := -1
+	if gracePeriod != "" {
+		n, err := strconv.Atoi(gracePeriod)
+		if err != nil {
+			log.Error("Invalid gracePeriod: " + gracePeriod)
+			return "Invalid gracePeriod: " + gracePeriod
+		}
+		grace = n
+	}
+	limit := 5 * time.Minute
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			log.Error("Invalid timeout: " + timeout)
+			return "Invalid timeout: " + timeout
+		}
+		limit = d
+	}
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	operations.Progress(ctx, "Cordoning "+node)
+	if err := setUnschedulable(ctx, node, true); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(ctx, metav1.ListOptions{FieldSelector: "spec.nodeName=" + node})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var evict, unmanaged []v1.Pod
+	for _, pod := range pods.Items {
+		if _, mirror := pod.Annotations[v1.MirrorPodAnnotationKey]; mirror {
+			operations.Step(ctx, pod.Namespace+"/"+pod.Name, "Skipped, mirror pod", nil)
+			continue
+		}
+		owner := metav1.GetControllerOf(&pod)
+		if owner != nil && owner.Kind == "DaemonSet" {
+			operations.Step(ctx, pod.Namespace+"/"+pod.Name, "Skipped, managed by DaemonSet "+owner.Name, nil)
+			continue
+		}
+		if owner == nil && pod.Status.Phase != v1.PodSucceeded && pod.Status.Phase != v1.PodFailed {
+			unmanaged = append(unmanaged, pod)
+		}
+		evict = append(evict, pod)
+	}
+	if len(unmanaged) > 0 && !force {
+		names := ""
+		for _, pod := range unmanaged {
+			names += " " + pod.Namespace + "/" + pod.Name
+		}
+		log.Error("Pods without a controller would be lost, drain again with force:" + names)
+		return "Pods without a controller would be lost, drain again with force:" + names
+	}
+
+	var gracePtr *int64
+	if grace >= 0 {
+		g := int64(grace)
+		gracePtr = &g
+	}
+	operations.Progress(ctx, "Evicting "+strconv.Itoa(len(evict))+" pods from "+node)
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	failed := 0
+	for i := range evict {
+		wg.Add(1)
+		go func(pod *v1.Pod) {
+			defer wg.Done()
+			name := pod.Namespace + "/" + pod.Name
+			err := evictPod(ctx, pod, gracePtr, log)
+			if err == nil {
+				operations.Progress(ctx, "Evicted "+name+", waiting for it to go")
+				err = waitForDeletion(ctx, pod)
+			}
+			operations.Step(ctx, name, "Evicted", err)
+			if err != nil {
+				log.Warn("Unable to evict " + name + ". Error: " + err.Error())
+				mu.Lock()
+				failed++
+				mu.Unlock()
+			}
+		}(&evict[i])
+	}
+	wg.Wait()
+
+	if failed > 0 {
+		log.Error("Node: " + node + " not drained, " + strconv.Itoa(failed) + " pods could not be evicted")
+		return "Node: " + node + " not drained, " + strconv.Itoa(failed) + " pods could not be evicted"
+	}
+	log.Info("Node: " + node + " Drained!")
+	return "Node: " + node + " Drained!"
+}
+
+// This function evicts a pod, retrying while a PodDisruptionBudget does not allow it yet
+func evictPod(ctx context.Context, pod *v1.Pod, grace *int64, log *logrus.Entry) error {
+	eviction := &policyv1.Eviction{
+		ObjectMeta:    metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace},
+		DeleteOptions: &metav1.DeleteOptions{GracePeriodSeconds: grace},
+	}
+	for {
+		err := Kconfig.PolicyV1().Evictions(pod.Namespace).Evict(ctx, eviction)
+		switch {
+		case err == nil, apierrors.IsNotFound(err):
+			return nil
+		case apierrors.IsTooManyRequests(err):
+			// The API answers 429 while evicting would break a PodDisruptionBudget
+			operations.Progress(ctx, "Eviction of "+pod.Namespace+"/"+pod.Name+" blocked by a PodDisruptionBudget, retrying")
+			log.Info("Eviction of " + pod.Name + " blocked: " + err.Error())
+		default:
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// This function waits until the pod is gone, or replaced by a new pod with the same name
+func waitForDeletion(ctx context.Context, pod *v1.Pod) error {
+	for {
+		current, err := Kconfig.CoreV1().Pods(pod.Namespace).Get(ctx, pod.Name, metav1.GetOptions{})
+		if apierrors.IsNotFound(err) || (err == nil && current.UID != pod.UID) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
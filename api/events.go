@@ -0,0 +1,221 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	eventsv1 "k8s.io/api/events/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+)
+
+// This function is used to get the events in the namespace, filtered and sorted as the query asks
+func Events(AgentNamespace string, query EventQuery, log *logrus.Entry) string {
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	if query.Order != "" && query.Order != "newest" && query.Order != "oldest" {
+		log.Error("Invalid order: " + query.Order)
+		return "Invalid order: " + query.Order + ", it must be newest or oldest"
+	}
+	since, err := eventTime(query.Since)
+	if err != nil {
+		log.Error("Invalid since: " + query.Since)
+		return "Invalid since: " + query.Since + ", it must be a duration like 1h or an RFC3339 time"
+	}
+	until, err := eventTime(query.Until)
+	if err != nil {
+		log.Error("Invalid until: " + query.Until)
+		return "Invalid until: " + query.Until + ", it must be a duration like 1h or an RFC3339 time"
+	}
+	if !since.IsZero() && !until.IsZero() && until.Before(since) {
+		log.Error("until is before since")
+		return "until is before since"
+	}
+
+	var eventsInfo []Event
+	switch query.API {
+	case "core", "":
+		eventsInfo, err = coreEvents(AgentNamespace, query)
+	case "events.k8s.io":
+		eventsInfo, err = eventsV1(AgentNamespace)
+	default:
+		log.Error("Invalid api: " + query.API)
+		return "Invalid api: " + query.API + ", it must be core or events.k8s.io"
+	}
+	if err != nil {
+		log.Error("Unable to find events. Error: " + err.Error())
+		return err.Error()
+	}
+
+	// The times are RFC3339, so they compare and sort as strings. An event is in the window when it was seen
+	// at some point between since and until.
+	var filtered []Event
+	for _, event := range eventsInfo {
+		if !query.matches(event) ||
+			(!since.IsZero() && event.LastSeen < rfc3339(since)) ||
+			(!until.IsZero() && event.FirstSeen > rfc3339(until)) {
+			continue
+		}
+		filtered = append(filtered, event)
+	}
+	sort.SliceStable(filtered, func(i, j int) bool {
+		if query.Order == "oldest" {
+			return filtered[i].LastSeen < filtered[j].LastSeen
+		}
+		return filtered[i].LastSeen > filtered[j].LastSeen
+	})
+
+	event_json, err := json.Marshal(filtered)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(event_json)
+}
+
+// This function reads since and until: a duration is that long ago, else it is an RFC3339 time. Empty is no bound.
+func eventTime(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if d, err := time.ParseDuration(value); err == nil {
+		return time.Now().Add(-d), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// Both APIs are filtered the same way in the end, so they give the same events for the same query
+func (query EventQuery) matches(event Event) bool {
+	return (query.Kind == "" || event.ObjectKind == query.Kind) &&
+		(query.Name == "" || event.ObjectName == query.Name) &&
+		(query.UID == "" || event.ObjectUID == query.UID) &&
+		(query.Type == "" || event.Type == query.Type) &&
+		(query.Reason == "" || event.Reason == query.Reason)
+}
+
+// The core API filters on the server with field selectors already
+func coreEvents(namespace string, query EventQuery) ([]Event, error) {
+	selector := fields.Set{}
+	for field, value := range map[string]string{
+		"involvedObject.kind": query.Kind,
+		"involvedObject.name": query.Name,
+		"involvedObject.uid":  query.UID,
+		"type":                query.Type,
+		"reason":              query.Reason,
+	} {
+		if value != "" {
+			selector[field] = value
+		}
+	}
+	events, err := Kconfig.CoreV1().Events(namespace).List(context.Background(), metav1.ListOptions{FieldSelector: selector.AsSelector().String()})
+	if err != nil {
+		return nil, err
+	}
+	var out []Event
+	for i := range events.Items {
+		out = append(out, coreEvent(&events.Items[i]))
+	}
+	return out, nil
+}
+
+// Events recorded through the newer API only fill eventTime and series, so the times fall back on those
+func coreEvent(event *v1.Event) Event {
+	info := Event{
+		Name:       event.Name,
+		Type:       event.Type,
+		Reason:     event.Reason,
+		Message:    event.Message,
+		ObjectKind: event.InvolvedObject.Kind,
+		ObjectName: event.InvolvedObject.Name,
+		ObjectUID:  string(event.InvolvedObject.UID),
+		Count:      event.Count,
+		FirstSeen:  firstTime(event.FirstTimestamp, event.EventTime, event.CreationTimestamp),
+		LastSeen:   firstTime(event.LastTimestamp, event.EventTime, event.CreationTimestamp),
+		Source:     event.Source.Component,
+		Action:     event.Action,
+		CreatedAt:  event.LastTimestamp.String(),
+		UniqueID:   string(event.UID),
+	}
+	if event.Source.Host != "" {
+		info.Source += " on " + event.Source.Host
+	}
+	if info.Source == "" {
+		info.Source = event.ReportingController
+	}
+	if event.Series != nil {
+		info.Series = &EventSeries{Count: event.Series.Count, LastObservedTime: rfc3339(event.Series.LastObservedTime.Time)}
+		info.Count = event.Series.Count
+		info.LastSeen = info.Series.LastObservedTime
+	}
+	if info.Count == 0 {
+		info.Count = 1
+	}
+	return info
+}
+
+// The events.k8s.io/v1 API is only filtered by Events, as its field selectors differ between server versions
+func eventsV1(namespace string) ([]Event, error) {
+	events, err := Kconfig.EventsV1().Events(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	var out []Event
+	for i := range events.Items {
+		out = append(out, eventV1(&events.Items[i]))
+	}
+	return out, nil
+}
+
+func eventV1(event *eventsv1.Event) Event {
+	info := Event{
+		Name:       event.Name,
+		Type:       event.Type,
+		Reason:     event.Reason,
+		Message:    event.Note,
+		ObjectKind: event.Regarding.Kind,
+		ObjectName: event.Regarding.Name,
+		ObjectUID:  string(event.Regarding.UID),
+		Count:      1,
+		FirstSeen:  firstTime(event.DeprecatedFirstTimestamp, event.EventTime, event.CreationTimestamp),
+		LastSeen:   firstTime(event.DeprecatedLastTimestamp, event.EventTime, event.CreationTimestamp),
+		Source:     event.ReportingController,
+		Action:     event.Action,
+		CreatedAt:  event.CreationTimestamp.String(),
+		UniqueID:   string(event.UID),
+	}
+	if event.ReportingInstance != "" {
+		info.Source += " on " + event.ReportingInstance
+	}
+	if event.DeprecatedCount > 0 {
+		info.Count = event.DeprecatedCount
+	}
+	if event.Series != nil {
+		info.Series = &EventSeries{Count: event.Series.Count, LastObservedTime: rfc3339(event.Series.LastObservedTime.Time)}
+		info.Count = event.Series.Count
+		info.LastSeen = info.Series.LastObservedTime
+	}
+	return info
+}
+
+// This function gives the first of the times that is set, in RFC3339
+func firstTime(t metav1.Time, eventTime metav1.MicroTime, created metav1.Time) string {
+	switch {
+	case !t.IsZero():
+		return rfc3339(t.Time)
+	case !eventTime.IsZero():
+		return rfc3339(eventTime.Time)
+	}
+	return rfc3339(created.Time)
+}
+
+func rfc3339(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
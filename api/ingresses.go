@@ -0,0 +1,374 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// These are all the Structs that are used for Ingresses
+type Ingress struct {
+	Name           string
+	IngressClass   string
+	Rules          []IngressRule
+	TLS            []IngressTLS
+	DefaultBackend *IngressBackend
+	LoadBalancer   []string
+	CreatedAt      string
+	UniqueID       string
+	Labels         map[string]string
+}
+
+type IngressRule struct {
+	Host  string
+	Paths []IngressPath
+}
+
+type IngressPath struct {
+	Path     string
+	PathType string
+	Backend  IngressBackend
+}
+
+type IngressBackend struct {
+	Service      string
+	Port         string
+	Resource     string
+	ServiceFound bool
+	Endpoints    []Endpoint
+}
+
+type IngressTLS struct {
+	Hosts      []string
+	SecretName string
+}
+
+type Ingressclass struct {
+	Name       string
+	Controller string
+	Default    bool
+	Parameters string
+	CreatedAt  string
+	UniqueID   string
+}
+
+type IngressMatch struct {
+	Namespace    string
+	Ingress      string
+	IngressClass string
+	Host         string
+	Path         string
+	PathType     string
+	Backend      IngressBackend
+}
+
+// Endpoint is one address behind a Service, as reported by its EndpointSlices
+type Endpoint struct {
+	Address  string
+	Ready    bool
+	Pod      string
+	NodeName string
+}
+
+// This function is used to get the list of all the Ingresses in the namespace with their backends resolved
+func Ingresses(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	ingresses, err := clientset.NetworkingV1().Ingresses(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find Ingresses. Error: " + err.Error())
+		return err.Error()
+	}
+	var ingressInfo []Ingress
+	for i := 0; i < len(ingresses.Items); i++ {
+		ingressInfo = append(ingressInfo, ingressDetails(&ingresses.Items[i], log))
+	}
+	ingress_json, err := json.Marshal(ingressInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(ingress_json)
+}
+
+func ingressClassName(ing *networkingv1.Ingress) string {
+	if ing.Spec.IngressClassName != nil {
+		return *ing.Spec.IngressClassName
+	}
+	// Older ingresses still pick their class with the annotation
+	return ing.Annotations["kubernetes.io/ingress.class"]
+}
+
+func ingressDetails(ing *networkingv1.Ingress, log *logrus.Entry) Ingress {
+	info := Ingress{
+		Name:      ing.Name,
+		CreatedAt: ing.CreationTimestamp.String(),
+		UniqueID:  string(ing.UID),
+		Labels:    ing.Labels,
+	}
+	info.IngressClass = ingressClassName(ing)
+	if ing.Spec.DefaultBackend != nil {
+		backend := resolveBackend(ing.Namespace, ing.Spec.DefaultBackend, log)
+		info.DefaultBackend = &backend
+	}
+	for _, rule := range ing.Spec.Rules {
+		ruleInfo := IngressRule{Host: rule.Host}
+		if rule.HTTP != nil {
+			for _, path := range rule.HTTP.Paths {
+				ruleInfo.Paths = append(ruleInfo.Paths, IngressPath{
+					Path:     path.Path,
+					PathType: ingressPathType(path),
+					Backend:  resolveBackend(ing.Namespace, &path.Backend, log),
+				})
+			}
+		}
+		info.Rules = append(info.Rules, ruleInfo)
+	}
+	for _, tls := range ing.Spec.TLS {
+		info.TLS = append(info.TLS, IngressTLS{Hosts: tls.Hosts, SecretName: tls.SecretName})
+	}
+	for _, lb := range ing.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			info.LoadBalancer = append(info.LoadBalancer, lb.Hostname)
+		} else {
+			info.LoadBalancer = append(info.LoadBalancer, lb.IP)
+		}
+	}
+	return info
+}
+
+func ingressPathType(path networkingv1.HTTPIngressPath) string {
+	if path.PathType == nil {
+		return string(networkingv1.PathTypeImplementationSpecific)
+	}
+	return string(*path.PathType)
+}
+
+// This function resolves an Ingress backend to its Service and the endpoints behind it
+func resolveBackend(namespace string, backend *networkingv1.IngressBackend, log *logrus.Entry) IngressBackend {
+	if backend.Resource != nil {
+		return IngressBackend{Resource: backend.Resource.Kind + "/" + backend.Resource.Name}
+	}
+	if backend.Service == nil {
+		return IngressBackend{}
+	}
+	info := IngressBackend{Service: backend.Service.Name, Port: backend.Service.Port.Name}
+	if info.Port == "" {
+		info.Port = strconv.Itoa(int(backend.Service.Port.Number))
+	}
+	_, err := Kconfig.CoreV1().Services(namespace).Get(context.Background(), backend.Service.Name, metav1.GetOptions{})
+	if err != nil {
+		log.Warn("Backend Service " + backend.Service.Name + " not found. Error: " + err.Error())
+		return info
+	}
+	info.ServiceFound = true
+	endpoints, err := serviceEndpoints(namespace, backend.Service.Name)
+	if err != nil {
+		log.Error(err.Error())
+		return info
+	}
+	info.Endpoints = endpoints
+	return info
+}
+
+// This function returns the addresses behind a Service from its EndpointSlices
+func serviceEndpoints(namespace string, service string) ([]Endpoint, error) {
+	clientset := Kconfig
+	slices, err := clientset.DiscoveryV1().EndpointSlices(namespace).List(context.Background(), metav1.ListOptions{
+		LabelSelector: discoveryv1.LabelServiceName + "=" + service,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var endpoints []Endpoint
+	for i := range slices.Items {
+		endpoints = append(endpoints, sliceEndpoints(&slices.Items[i])...)
+	}
+	return endpoints, nil
+}
+
+func sliceEndpoints(slice *discoveryv1.EndpointSlice) []Endpoint {
+	var endpoints []Endpoint
+	for _, ep := range slice.Endpoints {
+		// A nil ready condition means ready, as the API documents
+		ready := ep.Conditions.Ready == nil || *ep.Conditions.Ready
+		for _, address := range ep.Addresses {
+			endpoint := Endpoint{Address: address, Ready: ready}
+			if ep.TargetRef != nil && ep.TargetRef.Kind == "Pod" {
+				endpoint.Pod = ep.TargetRef.Name
+			}
+			if ep.NodeName != nil {
+				endpoint.NodeName = *ep.NodeName
+			}
+			endpoints = append(endpoints, endpoint)
+		}
+	}
+	return endpoints
+}
+
+// This function is used to get the list of all the IngressClasses in the cluster
+func IngressClasses(log *logrus.Entry) string {
+	clientset := Kconfig
+	classes, err := clientset.NetworkingV1().IngressClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find IngressClasses. Error: " + err.Error())
+		return err.Error()
+	}
+	var classInfo []Ingressclass
+	for i := 0; i < len(classes.Items); i++ {
+		class := classes.Items[i]
+		info := Ingressclass{
+			Name:       class.Name,
+			Controller: class.Spec.Controller,
+			Default:    class.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true",
+			CreatedAt:  class.CreationTimestamp.String(),
+			UniqueID:   string(class.UID),
+		}
+		if params := class.Spec.Parameters; params != nil {
+			info.Parameters = params.Kind + "/" + params.Name
+		}
+		classInfo = append(classInfo, info)
+	}
+	class_json, err := json.Marshal(classInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(class_json)
+}
+
+// This function answers which Ingress rule serves a host and path. Host and path routing is shared by the whole
+// cluster, so the Ingresses of every namespace are looked at, only those of ingressClass when it is set (Ingresses
+// without a class count for the default class). The Ingress matching rules decide: an exact host beats a wildcard
+// host, the longest path wins and Exact beats Prefix on a tie; after that the oldest Ingress wins, as the controllers
+// do. When no rule matches, the default backend of the oldest Ingress that has one serves the request.
+func IngressLookup(host string, path string, ingressClass string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if path == "" {
+		path = "/"
+	}
+	list, err := clientset.NetworkingV1().Ingresses(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find Ingresses. Error: " + err.Error())
+		return err.Error()
+	}
+	defaultClass := false
+	if ingressClass != "" {
+		class, err := clientset.NetworkingV1().IngressClasses().Get(context.Background(), ingressClass, metav1.GetOptions{})
+		if err == nil {
+			defaultClass = class.Annotations[networkingv1.AnnotationIsDefaultIngressClass] == "true"
+		}
+	}
+	var ingresses []*networkingv1.Ingress
+	for i := range list.Items {
+		ing := &list.Items[i]
+		if class := ingressClassName(ing); ingressClass == "" || class == ingressClass || (class == "" && defaultClass) {
+			ingresses = append(ingresses, ing)
+		}
+	}
+	match, matchBackend := matchIngress(ingresses, host, path)
+	if match == nil {
+		log.Info("No Ingress rule serves " + host + path)
+		return "No Ingress rule serves " + host + path
+	}
+	match.Backend = resolveBackend(match.Namespace, matchBackend, log)
+
+	match_json, err := json.Marshal(match)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(match_json)
+}
+
+// This function picks the rule that serves host and path: the most specific host, then the longest path with Exact before Prefix,
+// and on a tie the oldest Ingress. Without a matching rule the default backend of the oldest Ingress that has one is used.
+func matchIngress(ingresses []*networkingv1.Ingress, host string, path string) (*IngressMatch, *networkingv1.IngressBackend) {
+	sort.SliceStable(ingresses, func(a, b int) bool {
+		ta, tb := ingresses[a].CreationTimestamp, ingresses[b].CreationTimestamp
+		if !ta.Equal(&tb) {
+			return ta.Before(&tb)
+		}
+		return ingresses[a].Namespace+"/"+ingresses[a].Name < ingresses[b].Namespace+"/"+ingresses[b].Name
+	})
+
+	var match *IngressMatch
+	var matchBackend *networkingv1.IngressBackend
+	bestHost, bestPath, bestExact := -1, -1, false
+	for _, ing := range ingresses {
+		for _, rule := range ing.Spec.Rules {
+			hostScore := ingressHostScore(rule.Host, host)
+			if hostScore < 0 || rule.HTTP == nil {
+				continue
+			}
+			for j := range rule.HTTP.Paths {
+				p := &rule.HTTP.Paths[j]
+				pathType := ingressPathType(*p)
+				if !ingressPathMatches(pathType, p.Path, path) {
+					continue
+				}
+				exact := pathType == string(networkingv1.PathTypeExact)
+				better := hostScore > bestHost ||
+					(hostScore == bestHost && len(p.Path) > bestPath) ||
+					(hostScore == bestHost && len(p.Path) == bestPath && exact && !bestExact)
+				if !better {
+					continue
+				}
+				bestHost, bestPath, bestExact = hostScore, len(p.Path), exact
+				match = &IngressMatch{Namespace: ing.Namespace, Ingress: ing.Name, IngressClass: ingressClassName(ing), Host: rule.Host, Path: p.Path, PathType: pathType}
+				matchBackend = &p.Backend
+			}
+		}
+	}
+	if match == nil {
+		for _, ing := range ingresses {
+			if ing.Spec.DefaultBackend != nil {
+				match = &IngressMatch{Namespace: ing.Namespace, Ingress: ing.Name, IngressClass: ingressClassName(ing), PathType: "DefaultBackend"}
+				matchBackend = ing.Spec.DefaultBackend
+				break
+			}
+		}
+	}
+	return match, matchBackend
+}
+
+// This function scores how well a rule host matches: 2 for exact, 1 for a wildcard, 0 for a rule without host, -1 for no match
+func ingressHostScore(ruleHost string, host string) int {
+	switch {
+	case ruleHost == "":
+		return 0
+	case ruleHost == host:
+		return 2
+	case strings.HasPrefix(ruleHost, "*."):
+		// A wildcard only covers a single DNS label
+		suffix := ruleHost[1:]
+		if strings.HasSuffix(host, suffix) && !strings.Contains(strings.TrimSuffix(host, suffix), ".") && len(host) > len(suffix) {
+			return 1
+		}
+	}
+	return -1
+}
+
+// Prefix paths match element by element, so /foo matches /foo and /foo/bar but not /foobar
+func ingressPathMatches(pathType string, rulePath string, path string) bool {
+	if pathType == string(networkingv1.PathTypeExact) {
+		return rulePath == path
+	}
+	rulePath = strings.TrimSuffix(rulePath, "/")
+	if rulePath == "" {
+		return true
+	}
+	return path == rulePath || strings.HasPrefix(path, rulePath+"/")
+}
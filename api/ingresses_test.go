@@ -0,0 +1,180 @@
+package api
+
+import (
+	"testing"
+	"time"
+
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func testIngressPath(path string, pathType networkingv1.PathType, service string) networkingv1.HTTPIngressPath {
+	return networkingv1.HTTPIngressPath{
+		Path:     path,
+		PathType: &pathType,
+		Backend:  networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: service}},
+	}
+}
+
+func testIngress(namespace string, name string, age time.Duration, host string, paths ...networkingv1.HTTPIngressPath) *networkingv1.Ingress {
+	return &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:         namespace,
+			Name:              name,
+			CreationTimestamp: metav1.NewTime(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC).Add(-age)),
+		},
+		Spec: networkingv1.IngressSpec{Rules: []networkingv1.IngressRule{{
+			Host:             host,
+			IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{Paths: paths}},
+		}}},
+	}
+}
+
+func TestIngressHostScore(t *testing.T) {
+	tests := []struct {
+		ruleHost, host string
+		want           int
+	}{
+		{"shop.example.com", "shop.example.com", 2},
+		{"*.example.com", "shop.example.com", 1},
+		{"*.example.com", "a.shop.example.com", -1},
+		{"*.example.com", "example.com", -1},
+		{"", "shop.example.com", 0},
+		{"blog.example.com", "shop.example.com", -1},
+	}
+	for _, tt := range tests {
+		if got := ingressHostScore(tt.ruleHost, tt.host); got != tt.want {
+			t.Errorf("ingressHostScore(%q, %q) = %d, want %d", tt.ruleHost, tt.host, got, tt.want)
+		}
+	}
+}
+
+func TestIngressPathMatches(t *testing.T) {
+	tests := []struct {
+		pathType networkingv1.PathType
+		rulePath string
+		path     string
+		want     bool
+	}{
+		{networkingv1.PathTypePrefix, "/", "/anything", true},
+		{networkingv1.PathTypePrefix, "/foo", "/foo", true},
+		{networkingv1.PathTypePrefix, "/foo", "/foo/bar", true},
+		{networkingv1.PathTypePrefix, "/foo/", "/foo", true},
+		{networkingv1.PathTypePrefix, "/foo", "/foobar", false},
+		{networkingv1.PathTypeExact, "/foo", "/foo", true},
+		{networkingv1.PathTypeExact, "/foo", "/foo/", false},
+		{networkingv1.PathTypeImplementationSpecific, "/foo", "/foo/bar", true},
+	}
+	for _, tt := range tests {
+		if got := ingressPathMatches(string(tt.pathType), tt.rulePath, tt.path); got != tt.want {
+			t.Errorf("ingressPathMatches(%s, %q, %q) = %v, want %v", tt.pathType, tt.rulePath, tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMatchIngress(t *testing.T) {
+	prefix, exact := networkingv1.PathTypePrefix, networkingv1.PathTypeExact
+	withDefault := func(ing *networkingv1.Ingress, service string) *networkingv1.Ingress {
+		ing.Spec.DefaultBackend = &networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{Name: service}}
+		return ing
+	}
+
+	tests := []struct {
+		name        string
+		ingresses   []*networkingv1.Ingress
+		host, path  string
+		wantIngress string
+		wantService string
+		wantType    string
+	}{
+		{
+			name: "exact host beats wildcard host",
+			ingresses: []*networkingv1.Ingress{
+				testIngress("shop", "wildcard", time.Hour, "*.example.com", testIngressPath("/api", prefix, "wildcard")),
+				testIngress("shop", "exact", 0, "shop.example.com", testIngressPath("/", prefix, "exact")),
+			},
+			host: "shop.example.com", path: "/api/orders",
+			wantIngress: "shop/exact", wantService: "exact", wantType: "Prefix",
+		},
+		{
+			name: "wildcard host beats a rule without host",
+			ingresses: []*networkingv1.Ingress{
+				testIngress("shop", "any", time.Hour, "", testIngressPath("/", prefix, "any")),
+				testIngress("shop", "wildcard", 0, "*.example.com", testIngressPath("/", prefix, "wildcard")),
+			},
+			host: "shop.example.com", path: "/",
+			wantIngress: "shop/wildcard", wantService: "wildcard", wantType: "Prefix",
+		},
+		{
+			name: "wildcard does not cover two labels",
+			ingresses: []*networkingv1.Ingress{
+				testIngress("shop", "wildcard", 0, "*.example.com", testIngressPath("/", prefix, "wildcard")),
+			},
+			host: "a.shop.example.com", path: "/",
+		},
+		{
+			name: "longest path wins",
+			ingresses: []*networkingv1.Ingress{
+				testIngress("shop", "root", time.Hour, "shop.example.com", testIngressPath("/", prefix, "root")),
+				testIngress("shop", "api", 0, "shop.example.com", testIngressPath("/api", prefix, "api")),
+			},
+			host: "shop.example.com", path: "/api/orders",
+			wantIngress: "shop/api", wantService: "api", wantType: "Prefix",
+		},
+		{
+			name: "exact beats prefix on the same path",
+			ingresses: []*networkingv1.Ingress{
+				testIngress("shop", "prefix", time.Hour, "shop.example.com", testIngressPath("/api", prefix, "prefix")),
+				testIngress("shop", "exact", 0, "shop.example.com", testIngressPath("/api", exact, "exact")),
+			},
+			host: "shop.example.com", path: "/api",
+			wantIngress: "shop/exact", wantService: "exact", wantType: "Exact",
+		},
+		{
+			name: "oldest Ingress wins a tie across namespaces",
+			ingresses: []*networkingv1.Ingress{
+				testIngress("new", "shop", 0, "shop.example.com", testIngressPath("/", prefix, "new")),
+				testIngress("old", "shop", time.Hour, "shop.example.com", testIngressPath("/", prefix, "old")),
+			},
+			host: "shop.example.com", path: "/",
+			wantIngress: "old/shop", wantService: "old", wantType: "Prefix",
+		},
+		{
+			name: "default backend of the oldest Ingress when no rule matches",
+			ingresses: []*networkingv1.Ingress{
+				withDefault(testIngress("shop", "new", 0, "shop.example.com", testIngressPath("/api", exact, "api")), "new-default"),
+				withDefault(testIngress("shop", "old", time.Hour, "shop.example.com"), "old-default"),
+			},
+			host: "shop.example.com", path: "/other",
+			wantIngress: "shop/old", wantService: "old-default", wantType: "DefaultBackend",
+		},
+		{
+			name:      "no match",
+			ingresses: []*networkingv1.Ingress{testIngress("shop", "shop", 0, "shop.example.com", testIngressPath("/", prefix, "shop"))},
+			host:      "blog.example.com", path: "/",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			match, backend := matchIngress(tt.ingresses, tt.host, tt.path)
+			if tt.wantIngress == "" {
+				if match != nil {
+					t.Fatalf("got %s/%s, want no match", match.Namespace, match.Ingress)
+				}
+				return
+			}
+			if match == nil {
+				t.Fatalf("got no match, want %s", tt.wantIngress)
+			}
+			if got := match.Namespace + "/" + match.Ingress; got != tt.wantIngress {
+				t.Errorf("Ingress = %s, want %s", got, tt.wantIngress)
+			}
+			if match.PathType != tt.wantType {
+				t.Errorf("PathType = %s, want %s", match.PathType, tt.wantType)
+			}
+			if backend.Service.Name != tt.wantService {
+				t.Errorf("Service = %s, want %s", backend.Service.Name, tt.wantService)
+			}
+		})
+	}
+}
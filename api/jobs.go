@@ -0,0 +1,295 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	batchv1 "k8s.io/api/batch/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// These are all the Structs that are used for Jobs and CronJobs
+type Job struct {
+	Name           string
+	Status         string
+	Completions    int32
+	Active         int32
+	Succeeded      int32
+	Failed         int32
+	StartTime      string
+	CompletionTime string
+	Duration       string
+	CronJob        string
+	CreatedAt      string
+	UniqueID       string
+	Labels         map[string]string
+}
+
+type Cronjob struct {
+	Name               string
+	Schedule           string
+	Suspend            bool
+	Active             []string
+	LastScheduleTime   string
+	LastSuccessfulTime string
+	CreatedAt          string
+	UniqueID           string
+	Labels             map[string]string
+}
+
+// This function is used to get the list of all the Jobs in the namespace
+func Jobs(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	jobs, err := clientset.BatchV1().Jobs(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find Jobs. Error: " + err.Error())
+		return err.Error()
+	}
+	var jobInfo []Job
+	for i := 0; i < len(jobs.Items); i++ {
+		jobInfo = append(jobInfo, jobDetails(&jobs.Items[i]))
+	}
+	job_json, err := json.Marshal(jobInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(job_json)
+}
+
+func jobDetails(job *batchv1.Job) Job {
+	info := Job{
+		Name:        job.Name,
+		Status:      jobStatus(job),
+		Completions: 1,
+		Active:      job.Status.Active,
+		Succeeded:   job.Status.Succeeded,
+		Failed:      job.Status.Failed,
+		CreatedAt:   job.CreationTimestamp.String(),
+		UniqueID:    string(job.UID),
+		Labels:      job.Labels,
+	}
+	if job.Spec.Completions != nil {
+		info.Completions = *job.Spec.Completions
+	}
+	if owner := metav1.GetControllerOf(job); owner != nil && owner.Kind == "CronJob" {
+		info.CronJob = owner.Name
+	}
+	if job.Status.StartTime != nil {
+		info.StartTime = job.Status.StartTime.String()
+		end := time.Now()
+		if job.Status.CompletionTime != nil {
+			info.CompletionTime = job.Status.CompletionTime.String()
+			end = job.Status.CompletionTime.Time
+		} else {
+			// A failed Job never gets a completion time, it ended when it was marked Failed
+			for _, condition := range job.Status.Conditions {
+				if condition.Type == batchv1.JobFailed && condition.Status == v1.ConditionTrue {
+					end = condition.LastTransitionTime.Time
+				}
+			}
+		}
+		info.Duration = end.Sub(job.Status.StartTime.Time).Round(time.Second).String()
+	}
+	return info
+}
+
+// A Job is finished once it has a Complete or Failed condition, until then it is Running only while it has active pods
+func jobStatus(job *batchv1.Job) string {
+	for _, condition := range job.Status.Conditions {
+		if condition.Status != v1.ConditionTrue {
+			continue
+		}
+		switch condition.Type {
+		case batchv1.JobComplete:
+			return "Complete"
+		case batchv1.JobFailed:
+			return "Failed"
+		case batchv1.JobSuspended:
+			return "Suspended"
+		}
+	}
+	switch {
+	case job.Spec.Suspend != nil && *job.Spec.Suspend:
+		return "Suspended"
+	case job.Status.Active == 0:
+		// No pod running yet, or the next one not started
+		return "Pending"
+	}
+	return "Running"
+}
+
+// This function is used to get the list of all the CronJobs in the namespace
+func CronJobs(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	cronjobs, err := clientset.BatchV1().CronJobs(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find CronJobs. Error: " + err.Error())
+		return err.Error()
+	}
+	var cronjobInfo []Cronjob
+	for i := 0; i < len(cronjobs.Items); i++ {
+		cronjob := cronjobs.Items[i]
+		info := Cronjob{
+			Name:      cronjob.Name,
+			Schedule:  cronjob.Spec.Schedule,
+			Suspend:   cronjob.Spec.Suspend != nil && *cronjob.Spec.Suspend,
+			CreatedAt: cronjob.CreationTimestamp.String(),
+			UniqueID:  string(cronjob.UID),
+			Labels:    cronjob.Labels,
+		}
+		for _, active := range cronjob.Status.Active {
+			info.Active = append(info.Active, active.Name)
+		}
+		if cronjob.Status.LastScheduleTime != nil {
+			info.LastScheduleTime = cronjob.Status.LastScheduleTime.String()
+		}
+		if cronjob.Status.LastSuccessfulTime != nil {
+			info.LastSuccessfulTime = cronjob.Status.LastSuccessfulTime.String()
+		}
+		cronjobInfo = append(cronjobInfo, info)
+	}
+	cronjob_json, err := json.Marshal(cronjobInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(cronjob_json)
+}
+
+// This function runs a CronJob right now by creating a Job from its template, like kubectl create job --from
+func TriggerCronJob(namespace string, cronjob string, log *logrus.Entry) string {
+	clientset := Kconfig
+	cj, err := clientset.BatchV1().CronJobs(namespace).Get(context.Background(), cronjob, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	// Job names are capped at 63 characters, the suffix keeps them unique
+	suffix := "-manual-" + strconv.FormatInt(time.Now().Unix(), 36)
+	name := cj.Name
+	if len(name)+len(suffix) > 63 {
+		name = name[:63-len(suffix)]
+	}
+	annotations := map[string]string{"cronjob.kubernetes.io/instantiate": "manual"}
+	for key, value := range cj.Spec.JobTemplate.Annotations {
+		annotations[key] = value
+	}
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:            name + suffix,
+			Namespace:       namespace,
+			Labels:          cj.Spec.JobTemplate.Labels,
+			Annotations:     annotations,
+			OwnerReferences: []metav1.OwnerReference{*metav1.NewControllerRef(cj, batchv1.SchemeGroupVersion.WithKind("CronJob"))},
+		},
+		Spec: cj.Spec.JobTemplate.Spec,
+	}
+	created, err := clientset.BatchV1().Jobs(namespace).Create(context.Background(), job, metav1.CreateOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("CronJob: " + cronjob + " Triggered as Job: " + created.Name)
+	return "CronJob: " + cronjob + " Triggered as Job: " + created.Name
+}
+
+// This function suspends or resumes a CronJob
+func SuspendCronJob(namespace string, cronjob string, suspend bool, log *logrus.Entry) string {
+	clientset := Kconfig
+	patch := []byte(fmt.Sprintf(`{"spec":{"suspend":%t}}`, suspend))
+	_, err := clientset.BatchV1().CronJobs(namespace).Patch(context.Background(), cronjob, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if suspend {
+		log.Info("CronJob: " + cronjob + " Suspended!")
+		return "CronJob: " + cronjob + " Suspended!"
+	}
+	log.Info("CronJob: " + cronjob + " Resumed!")
+	return "CronJob: " + cronjob + " Resumed!"
+}
+
+// This function is used to get the Jobs a CronJob has created, newest first
+func CronJobHistory(namespace string, cronjob string, log *logrus.Entry) string {
+	clientset := Kconfig
+	cj, err := clientset.BatchV1().CronJobs(namespace).Get(context.Background(), cronjob, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	jobs, err := clientset.BatchV1().Jobs(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var owned []batchv1.Job
+	for _, job := range jobs.Items {
+		if owner := metav1.GetControllerOf(&job); owner != nil && owner.UID == cj.UID {
+			owned = append(owned, job)
+		}
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		return owned[j].CreationTimestamp.Before(&owned[i].CreationTimestamp)
+	})
+	var jobInfo []Job
+	for i := 0; i < len(owned); i++ {
+		jobInfo = append(jobInfo, jobDetails(&owned[i]))
+	}
+	job_json, err := json.Marshal(jobInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(job_json)
+}
+
+// This function is used to get the logs of all the pods of a Job
+func JobLogs(namespace string, job string, log *logrus.Entry) string {
+	clientset := Kconfig
+	j, err := clientset.BatchV1().Jobs(namespace).Get(context.Background(), job, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	selector, err := metav1.LabelSelectorAsSelector(j.Spec.Selector)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if len(pods.Items) == 0 {
+		return "Job: " + job + " has no pods"
+	}
+	sort.Slice(pods.Items, func(i, k int) bool {
+		return pods.Items[i].CreationTimestamp.Before(&pods.Items[k].CreationTimestamp)
+	})
+	logs := ""
+	for _, pod := range pods.Items {
+		logs += "==> " + pod.Name + " <==\n" + PodLogs(namespace, pod.Name, log) + "\n"
+	}
+	return logs
+}
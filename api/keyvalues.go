@@ -0,0 +1,108 @@
+package api
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// KeyValueRequest is a change to the data of a ConfigMap or Secret. Data sets keys, adding or replacing them, and Remove
+// drops keys. ResourceVersion, when set, is the version the change was made against: the update fails if the object
+// changed since.
+type KeyValueRequest struct {
+	Data            map[string][]byte
+	Remove          []string
+	ResourceVersion string
+}
+
+// KeyValueResult is what the ConfigMap or Secret looks like once it is saved, the values are left out
+type KeyValueResult struct {
+	Kind            string
+	Name            string
+	Type            string
+	ResourceVersion string
+	Keys            []string
+}
+
+// This function reads keys and values from a body, either a JSON object of strings (format json, the default) or
+// an env-file (format env): KEY=VALUE lines, blank lines and lines starting with # are skipped.
+func KeyValues(format string, body []byte) (map[string][]byte, error) {
+	data := map[string][]byte{}
+	switch format {
+	case "json", "":
+		var values map[string]string
+		if err := json.Unmarshal(body, &values); err != nil {
+			return nil, errors.New("Body must be a JSON object of keys to string values. Error: " + err.Error())
+		}
+		for key, value := range values {
+			data[key] = []byte(value)
+		}
+	case "env":
+		scanner := bufio.NewScanner(bytes.NewReader(body))
+		line := 0
+		for scanner.Scan() {
+			line++
+			text := strings.TrimSpace(scanner.Text())
+			if text == "" || strings.HasPrefix(text, "#") {
+				continue
+			}
+			key, value, ok := strings.Cut(text, "=")
+			if !ok || key == "" {
+				return nil, errors.New("Line " + strconv.Itoa(line) + " of the env-file is not KEY=VALUE")
+			}
+			if _, exists := data[key]; exists {
+				return nil, errors.New("Key " + key + " is set twice in the env-file")
+			}
+			data[key] = []byte(value)
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, errors.New("Invalid format: " + format + ", it must be json or env")
+	}
+	return data, nil
+}
+
+// This function checks the keys of the request, update tells whether it changes an object that is there already
+func (request KeyValueRequest) check(update bool) error {
+	var problems []string
+	for key := range request.Data {
+		for _, msg := range validation.IsConfigMapKey(key) {
+			problems = append(problems, "Invalid key "+strconv.Quote(key)+": "+msg)
+		}
+	}
+	for _, key := range request.Remove {
+		if _, ok := request.Data[key]; ok {
+			problems = append(problems, "Key "+key+" is both set and removed")
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return errors.New(strings.Join(problems, "; "))
+	}
+	if !update && (len(request.Remove) > 0 || request.ResourceVersion != "") {
+		return errors.New("remove and resourceVersion only apply to an update")
+	}
+	if update && len(request.Data) == 0 && len(request.Remove) == 0 {
+		return errors.New("Nothing to change, set or remove some keys")
+	}
+	return nil
+}
+
+func sortedKeys(keys ...map[string][]byte) []string {
+	var out []string
+	for _, m := range keys {
+		for key := range m {
+			out = append(out, key)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
@@ -0,0 +1,388 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+// These are all the Structs that are used for NetworkPolicies
+type Networkpolicy struct {
+	Name        string
+	PodSelector string
+	PolicyTypes []string
+	Ingress     []NetworkPolicyRule
+	Egress      []NetworkPolicyRule
+	CreatedAt   string
+	UniqueID    string
+	Labels      map[string]string
+}
+
+type NetworkPolicyRule struct {
+	Peers []string
+	Ports []string
+}
+
+// NetworkPolicyVerdict is what the evaluator works out for traffic from one pod to another
+type NetworkPolicyVerdict struct {
+	Allowed bool
+	Egress  NetworkPolicyDirection
+	Ingress NetworkPolicyDirection
+}
+
+// A pod is isolated in a direction once any policy selects it for that direction, then only the rules of those policies let traffic through
+type NetworkPolicyDirection struct {
+	Isolated  bool
+	Allowed   bool
+	AllowedBy []string
+	DeniedBy  []string
+}
+
+// This function is used to get the list of all the NetworkPolicies in the namespace
+func NetworkPolicies(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	policies, err := clientset.NetworkingV1().NetworkPolicies(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find NetworkPolicies. Error: " + err.Error())
+		return err.Error()
+	}
+	var policyInfo []Networkpolicy
+	for i := 0; i < len(policies.Items); i++ {
+		policy := policies.Items[i]
+		info := Networkpolicy{
+			Name:        policy.Name,
+			PodSelector: metav1.FormatLabelSelector(&policy.Spec.PodSelector),
+			CreatedAt:   policy.CreationTimestamp.String(),
+			UniqueID:    string(policy.UID),
+			Labels:      policy.Labels,
+		}
+		for _, policyType := range effectivePolicyTypes(&policy) {
+			info.PolicyTypes = append(info.PolicyTypes, string(policyType))
+		}
+		for _, rule := range policy.Spec.Ingress {
+			info.Ingress = append(info.Ingress, NetworkPolicyRule{Peers: peerStrings(rule.From), Ports: portStrings(rule.Ports)})
+		}
+		for _, rule := range policy.Spec.Egress {
+			info.Egress = append(info.Egress, NetworkPolicyRule{Peers: peerStrings(rule.To), Ports: portStrings(rule.Ports)})
+		}
+		policyInfo = append(policyInfo, info)
+	}
+	policy_json, err := json.Marshal(policyInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(policy_json)
+}
+
+// Without policyTypes a policy always covers Ingress, and Egress only when it has egress rules
+func effectivePolicyTypes(policy *networkingv1.NetworkPolicy) []networkingv1.PolicyType {
+	if len(policy.Spec.PolicyTypes) > 0 {
+		return policy.Spec.PolicyTypes
+	}
+	types := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	if len(policy.Spec.Egress) > 0 {
+		types = append(types, networkingv1.PolicyTypeEgress)
+	}
+	return types
+}
+
+func peerStrings(peers []networkingv1.NetworkPolicyPeer) []string {
+	if len(peers) == 0 {
+		return []string{"all"}
+	}
+	var out []string
+	for _, peer := range peers {
+		var parts []string
+		if peer.IPBlock != nil {
+			part := "ipBlock=" + peer.IPBlock.CIDR
+			if len(peer.IPBlock.Except) > 0 {
+				part += " except " + strings.Join(peer.IPBlock.Except, ",")
+			}
+			parts = append(parts, part)
+		}
+		if peer.NamespaceSelector != nil {
+			parts = append(parts, "namespaces: "+metav1.FormatLabelSelector(peer.NamespaceSelector))
+		}
+		if peer.PodSelector != nil {
+			parts = append(parts, "pods: "+metav1.FormatLabelSelector(peer.PodSelector))
+		}
+		out = append(out, strings.Join(parts, " and "))
+	}
+	return out
+}
+
+func portStrings(ports []networkingv1.NetworkPolicyPort) []string {
+	if len(ports) == 0 {
+		return []string{"all"}
+	}
+	var out []string
+	for _, port := range ports {
+		protocol := string(v1.ProtocolTCP)
+		if port.Protocol != nil {
+			protocol = string(*port.Protocol)
+		}
+		value := "all"
+		if port.Port != nil {
+			value = port.Port.String()
+			if port.EndPort != nil {
+				value += "-" + strconv.Itoa(int(*port.EndPort))
+			}
+		}
+		out = append(out, value+"/"+protocol)
+	}
+	return out
+}
+
+// This function works out from the NetworkPolicies and the pod and namespace labels whether the source pod
+// can reach the destination pod on the port, and which policy rules allow or deny it. Nothing is sent on the network.
+// port is a number or a named port of the destination pod, and can be left out to only count rules without ports.
+func NetworkPolicyCheck(srcNamespace string, srcPod string, dstNamespace string, dstPod string, port string, protocol string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if srcNamespace == "" {
+		srcNamespace = "default"
+	}
+	if dstNamespace == "" {
+		dstNamespace = srcNamespace
+	}
+	if protocol == "" {
+		protocol = string(v1.ProtocolTCP)
+	}
+	ctx := context.Background()
+	src, err := clientset.CoreV1().Pods(srcNamespace).Get(ctx, srcPod, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	dst, err := clientset.CoreV1().Pods(dstNamespace).Get(ctx, dstPod, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	srcNs, err := clientset.CoreV1().Namespaces().Get(ctx, srcNamespace, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	dstNs, err := clientset.CoreV1().Namespaces().Get(ctx, dstNamespace, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+
+	// A named port is looked up on the destination pod, as the API server would
+	portNumber := 0
+	if port != "" {
+		portNumber, err = strconv.Atoi(port)
+		if err != nil {
+			portNumber = namedPort(dst, port, protocol)
+			if portNumber == 0 {
+				log.Error("Pod " + dstPod + " has no port named " + port)
+				return "Pod " + dstPod + " has no port named " + port
+			}
+		}
+	}
+
+	srcPolicies, err := clientset.NetworkingV1().NetworkPolicies(srcNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	dstPolicies, err := clientset.NetworkingV1().NetworkPolicies(dstNamespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+
+	verdict := evaluateNetworkPolicies(src, dst, srcNs, dstNs, srcPolicies.Items, dstPolicies.Items, policyTarget{dst: dst, port: portNumber, protocol: protocol})
+
+	verdict_json, err := json.Marshal(verdict)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(verdict_json)
+}
+
+// This function decides the verdict from the policies of both namespaces. A pod is isolated in a direction once a
+// policy of that type selects it, and then a connection is only allowed when a rule of one of those policies allows it.
+func evaluateNetworkPolicies(src *v1.Pod, dst *v1.Pod, srcNs *v1.Namespace, dstNs *v1.Namespace, srcPolicies []networkingv1.NetworkPolicy, dstPolicies []networkingv1.NetworkPolicy, target policyTarget) NetworkPolicyVerdict {
+	verdict := NetworkPolicyVerdict{
+		Egress:  NetworkPolicyDirection{Allowed: true},
+		Ingress: NetworkPolicyDirection{Allowed: true},
+	}
+
+	// Egress: the policies of the source namespace that select the source pod
+	for i := range srcPolicies {
+		policy := &srcPolicies[i]
+		if !hasPolicyType(policy, networkingv1.PolicyTypeEgress) || !selectorMatches(&policy.Spec.PodSelector, src.Labels) {
+			continue
+		}
+		verdict.Egress.Isolated = true
+		allowed := false
+		for j, rule := range policy.Spec.Egress {
+			if peersMatch(rule.To, policy.Namespace, dst, dstNs) && target.portsMatch(rule.Ports) {
+				verdict.Egress.AllowedBy = append(verdict.Egress.AllowedBy, policy.Name+"/egress["+strconv.Itoa(j)+"]")
+				allowed = true
+			}
+		}
+		if !allowed {
+			verdict.Egress.DeniedBy = append(verdict.Egress.DeniedBy, policy.Name)
+		}
+	}
+	verdict.Egress.Allowed = !verdict.Egress.Isolated || len(verdict.Egress.AllowedBy) > 0
+
+	// Ingress: the policies of the destination namespace that select the destination pod
+	for i := range dstPolicies {
+		policy := &dstPolicies[i]
+		if !hasPolicyType(policy, networkingv1.PolicyTypeIngress) || !selectorMatches(&policy.Spec.PodSelector, dst.Labels) {
+			continue
+		}
+		verdict.Ingress.Isolated = true
+		allowed := false
+		for j, rule := range policy.Spec.Ingress {
+			if peersMatch(rule.From, policy.Namespace, src, srcNs) && target.portsMatch(rule.Ports) {
+				verdict.Ingress.AllowedBy = append(verdict.Ingress.AllowedBy, policy.Name+"/ingress["+strconv.Itoa(j)+"]")
+				allowed = true
+			}
+		}
+		if !allowed {
+			verdict.Ingress.DeniedBy = append(verdict.Ingress.DeniedBy, policy.Name)
+		}
+	}
+	verdict.Ingress.Allowed = !verdict.Ingress.Isolated || len(verdict.Ingress.AllowedBy) > 0
+	verdict.Allowed = verdict.Egress.Allowed && verdict.Ingress.Allowed
+	return verdict
+}
+
+func hasPolicyType(policy *networkingv1.NetworkPolicy, policyType networkingv1.PolicyType) bool {
+	for _, t := range effectivePolicyTypes(policy) {
+		if t == policyType {
+			return true
+		}
+	}
+	return false
+}
+
+func selectorMatches(selector *metav1.LabelSelector, podLabels map[string]string) bool {
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false
+	}
+	return s.Matches(labels.Set(podLabels))
+}
+
+// An empty peer list means every peer. A peer with only a podSelector stays in the policy's namespace,
+// a namespaceSelector widens it to the selected namespaces and an ipBlock is matched against the pod IP.
+func peersMatch(peers []networkingv1.NetworkPolicyPeer, policyNamespace string, pod *v1.Pod, podNamespace *v1.Namespace) bool {
+	if len(peers) == 0 {
+		return true
+	}
+	for _, peer := range peers {
+		if peer.IPBlock != nil {
+			if ipBlockMatches(peer.IPBlock, pod.Status.PodIP) {
+				return true
+			}
+			continue
+		}
+		if peer.NamespaceSelector != nil {
+			if !selectorMatches(peer.NamespaceSelector, podNamespace.Labels) {
+				continue
+			}
+		} else if pod.Namespace != policyNamespace {
+			continue
+		}
+		if peer.PodSelector != nil && !selectorMatches(peer.PodSelector, pod.Labels) {
+			continue
+		}
+		return true
+	}
+	return false
+}
+
+func ipBlockMatches(block *networkingv1.IPBlock, ip string) bool {
+	address := net.ParseIP(ip)
+	if address == nil {
+		return false
+	}
+	_, cidr, err := net.ParseCIDR(block.CIDR)
+	if err != nil || !cidr.Contains(address) {
+		return false
+	}
+	for _, except := range block.Except {
+		if _, e, err := net.ParseCIDR(except); err == nil && e.Contains(address) {
+			return false
+		}
+	}
+	return true
+}
+
+type policyTarget struct {
+	dst      *v1.Pod
+	port     int
+	protocol string
+}
+
+// An empty port list means every port. Without a port to check, only rules without ports match.
+func (t policyTarget) portsMatch(ports []networkingv1.NetworkPolicyPort) bool {
+	if len(ports) == 0 {
+		return true
+	}
+	if t.port == 0 {
+		return false
+	}
+	for _, port := range ports {
+		protocol := string(v1.ProtocolTCP)
+		if port.Protocol != nil {
+			protocol = string(*port.Protocol)
+		}
+		if !strings.EqualFold(protocol, t.protocol) {
+			continue
+		}
+		if port.Port == nil {
+			return true
+		}
+		start := port.Port.IntValue()
+		if port.Port.Type == intstr.String {
+			start = namedPort(t.dst, port.Port.StrVal, protocol)
+		}
+		end := start
+		if port.EndPort != nil {
+			end = int(*port.EndPort)
+		}
+		if start != 0 && t.port >= start && t.port <= end {
+			return true
+		}
+	}
+	return false
+}
+
+func namedPort(pod *v1.Pod, name string, protocol string) int {
+	for _, container := range pod.Spec.Containers {
+		for _, port := range container.Ports {
+			portProtocol := string(v1.ProtocolTCP)
+			if port.Protocol != "" {
+				portProtocol = string(port.Protocol)
+			}
+			if port.Name == name && strings.EqualFold(portProtocol, protocol) {
+				return int(port.ContainerPort)
+			}
+		}
+	}
+	return 0
+}
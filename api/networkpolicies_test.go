@@ -0,0 +1,262 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+
+	v1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/intstr"
+)
+
+func testPod(namespace string, name string, ip string, labels map[string]string, ports ...v1.ContainerPort) *v1.Pod {
+	return &v1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, Labels: labels},
+		Spec:       v1.PodSpec{Containers: []v1.Container{{Name: "app", Ports: ports}}},
+		Status:     v1.PodStatus{PodIP: ip},
+	}
+}
+
+func testNamespace(name string, labels map[string]string) *v1.Namespace {
+	return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+}
+
+func testPolicy(namespace string, name string, selector map[string]string, types []networkingv1.PolicyType, ingress []networkingv1.NetworkPolicyIngressRule, egress []networkingv1.NetworkPolicyEgressRule) networkingv1.NetworkPolicy {
+	return networkingv1.NetworkPolicy{
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+		Spec: networkingv1.NetworkPolicySpec{
+			PodSelector: metav1.LabelSelector{MatchLabels: selector},
+			PolicyTypes: types,
+			Ingress:     ingress,
+			Egress:      egress,
+		},
+	}
+}
+
+func testPorts(protocol v1.Protocol, port intstr.IntOrString, endPort int32) []networkingv1.NetworkPolicyPort {
+	p := networkingv1.NetworkPolicyPort{Protocol: &protocol, Port: &port}
+	if endPort != 0 {
+		p.EndPort = &endPort
+	}
+	return []networkingv1.NetworkPolicyPort{p}
+}
+
+func TestEvaluateNetworkPolicies(t *testing.T) {
+	ingressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeIngress}
+	egressOnly := []networkingv1.PolicyType{networkingv1.PolicyTypeEgress}
+	frontendNs := testNamespace("frontend", map[string]string{"team": "web"})
+	backendNs := testNamespace("backend", map[string]string{"team": "api"})
+	web := testPod("frontend", "web", "10.0.1.5", map[string]string{"app": "web"})
+	api := testPod("backend", "api", "10.0.2.7", map[string]string{"app": "api"},
+		v1.ContainerPort{Name: "http", ContainerPort: 8080, Protocol: v1.ProtocolTCP})
+	other := testPod("backend", "other", "10.0.2.8", map[string]string{"app": "other"})
+	fromWebPods := []networkingv1.NetworkPolicyPeer{{PodSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}}}
+	fromWebNamespace := []networkingv1.NetworkPolicyPeer{{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}}}}
+
+	tests := []struct {
+		name        string
+		src, dst    *v1.Pod
+		srcPolicies []networkingv1.NetworkPolicy
+		dstPolicies []networkingv1.NetworkPolicy
+		port        int
+		protocol    string
+		want        NetworkPolicyVerdict
+	}{
+		{
+			name: "no policies allow everything",
+			src:  web, dst: api, port: 8080,
+			want: NetworkPolicyVerdict{Allowed: true, Egress: NetworkPolicyDirection{Allowed: true}, Ingress: NetworkPolicyDirection{Allowed: true}},
+		},
+		{
+			name: "default deny ingress",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "deny-all", nil, ingressOnly, nil, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"deny-all"}},
+			},
+		},
+		{
+			name: "default deny ingress does not isolate egress of the same pod",
+			src:  other, dst: api, port: 8080,
+			srcPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "deny-all", nil, ingressOnly, nil, nil)},
+			want:        NetworkPolicyVerdict{Allowed: true, Egress: NetworkPolicyDirection{Allowed: true}, Ingress: NetworkPolicyDirection{Allowed: true}},
+		},
+		{
+			name: "allow rules of different policies add up",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{
+				testPolicy("backend", "deny-all", nil, ingressOnly, nil, nil),
+				testPolicy("backend", "allow-web", map[string]string{"app": "api"}, ingressOnly,
+					[]networkingv1.NetworkPolicyIngressRule{{From: fromWebNamespace}}, nil),
+			},
+			want: NetworkPolicyVerdict{
+				Allowed: true,
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, Allowed: true, AllowedBy: []string{"allow-web/ingress[0]"}, DeniedBy: []string{"deny-all"}},
+			},
+		},
+		{
+			name: "namespace selector of another namespace does not match",
+			src:  other, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-web", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{From: fromWebNamespace}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"allow-web"}},
+			},
+		},
+		{
+			name: "pod selector alone stays in the policy namespace",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-web-pods", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{From: fromWebPods}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"allow-web-pods"}},
+			},
+		},
+		{
+			name: "namespace and pod selector in one peer must both match",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-web", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "web"}},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "admin"}},
+				}}}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"allow-web"}},
+			},
+		},
+		{
+			name: "ip block with an exception",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-cidr", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{From: []networkingv1.NetworkPolicyPeer{{
+					IPBlock: &networkingv1.IPBlock{CIDR: "10.0.0.0/16", Except: []string{"10.0.1.0/24"}},
+				}}}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"allow-cidr"}},
+			},
+		},
+		{
+			name: "port rule allows the port",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-8080", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{Ports: testPorts(v1.ProtocolTCP, intstr.FromInt(8080), 0)}}, nil)},
+			want: NetworkPolicyVerdict{
+				Allowed: true,
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, Allowed: true, AllowedBy: []string{"allow-8080/ingress[0]"}},
+			},
+		},
+		{
+			name: "named port and port range",
+			src:  web, dst: api, port: 8080,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-ports", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{
+					{Ports: testPorts(v1.ProtocolTCP, intstr.FromString("http"), 0)},
+					{Ports: testPorts(v1.ProtocolTCP, intstr.FromInt(8000), 8100)},
+				}, nil)},
+			want: NetworkPolicyVerdict{
+				Allowed: true,
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, Allowed: true, AllowedBy: []string{"allow-ports/ingress[0]", "allow-ports/ingress[1]"}},
+			},
+		},
+		{
+			name: "port rule of another protocol",
+			src:  web, dst: api, port: 8080, protocol: "UDP",
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-8080", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{Ports: testPorts(v1.ProtocolTCP, intstr.FromInt(8080), 0)}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"allow-8080"}},
+			},
+		},
+		{
+			name: "without a port only rules without ports match",
+			src:  web, dst: api,
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-8080", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{Ports: testPorts(v1.ProtocolTCP, intstr.FromInt(8080), 0)}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Allowed: true},
+				Ingress: NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"allow-8080"}},
+			},
+		},
+		{
+			name: "egress denied while ingress allows",
+			src:  web, dst: api, port: 8080,
+			srcPolicies: []networkingv1.NetworkPolicy{testPolicy("frontend", "deny-egress", nil, egressOnly, nil, nil)},
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-web", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{From: fromWebNamespace}}, nil)},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"deny-egress"}},
+				Ingress: NetworkPolicyDirection{Isolated: true, Allowed: true, AllowedBy: []string{"allow-web/ingress[0]"}},
+			},
+		},
+		{
+			name: "egress and ingress both allow",
+			src:  web, dst: api, port: 8080,
+			srcPolicies: []networkingv1.NetworkPolicy{testPolicy("frontend", "to-api", nil, egressOnly, nil,
+				[]networkingv1.NetworkPolicyEgressRule{{To: []networkingv1.NetworkPolicyPeer{{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "api"}},
+					PodSelector:       &metav1.LabelSelector{MatchLabels: map[string]string{"app": "api"}},
+				}}}})},
+			dstPolicies: []networkingv1.NetworkPolicy{testPolicy("backend", "allow-web", nil, ingressOnly,
+				[]networkingv1.NetworkPolicyIngressRule{{From: fromWebNamespace}}, nil)},
+			want: NetworkPolicyVerdict{
+				Allowed: true,
+				Egress:  NetworkPolicyDirection{Isolated: true, Allowed: true, AllowedBy: []string{"to-api/egress[0]"}},
+				Ingress: NetworkPolicyDirection{Isolated: true, Allowed: true, AllowedBy: []string{"allow-web/ingress[0]"}},
+			},
+		},
+		{
+			name: "without policyTypes egress rules make it an egress policy too",
+			src:  web, dst: api, port: 8080,
+			srcPolicies: []networkingv1.NetworkPolicy{testPolicy("frontend", "implicit", nil, nil, nil,
+				[]networkingv1.NetworkPolicyEgressRule{{Ports: testPorts(v1.ProtocolUDP, intstr.FromInt(53), 0)}})},
+			want: NetworkPolicyVerdict{
+				Egress:  NetworkPolicyDirection{Isolated: true, DeniedBy: []string{"implicit"}},
+				Ingress: NetworkPolicyDirection{Allowed: true},
+			},
+		},
+	}
+	namespaces := map[string]*v1.Namespace{"frontend": frontendNs, "backend": backendNs}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			protocol := tt.protocol
+			if protocol == "" {
+				protocol = "TCP"
+			}
+			target := policyTarget{dst: tt.dst, port: tt.port, protocol: protocol}
+			got := evaluateNetworkPolicies(tt.src, tt.dst, namespaces[tt.src.Namespace], namespaces[tt.dst.Namespace], tt.srcPolicies, tt.dstPolicies, target)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIPBlockMatches(t *testing.T) {
+	tests := []struct {
+		block networkingv1.IPBlock
+		ip    string
+		want  bool
+	}{
+		{networkingv1.IPBlock{CIDR: "10.0.0.0/8"}, "10.1.2.3", true},
+		{networkingv1.IPBlock{CIDR: "10.0.0.0/8"}, "192.168.0.1", false},
+		{networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}, "10.1.2.3", false},
+		{networkingv1.IPBlock{CIDR: "10.0.0.0/8", Except: []string{"10.1.0.0/16"}}, "10.2.2.3", true},
+		{networkingv1.IPBlock{CIDR: "10.0.0.0/8"}, "", false},
+		{networkingv1.IPBlock{CIDR: "fd00::/8"}, "fd00::1", true},
+	}
+	for _, tt := range tests {
+		if got := ipBlockMatches(&tt.block, tt.ip); got != tt.want {
+			t.Errorf("ipBlockMatches(%v, %q) = %v, want %v", tt.block, tt.ip, got, tt.want)
+		}
+	}
+}
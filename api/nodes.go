@@ -0,0 +1,185 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// These are all the Structs that are used for Nodes
+type Node struct {
+	Name             string
+	Ready            string
+	MemoryPressure   string
+	DiskPressure     string
+	PIDPressure      string
+	Unschedulable    bool
+	Conditions       []NodeCondition
+	Capacity         map[string]string
+	Allocatable      map[string]string
+	Requests         map[string]string
+	Taints           []string
+	InternalIP       string
+	KubeletVersion   string
+	ContainerRuntime string
+	OSImage          string
+	KernelVersion    string
+	OperatingSystem  string
+	Architecture     string
+	Pods             []NodePod
+	CreatedAt        string
+	UniqueID         string
+	Labels           map[string]string
+}
+
+type NodeCondition struct {
+	Type    string
+	Status  string
+	Reason  string
+	Message string
+}
+
+type NodePod struct {
+	Namespace     string
+	Name          string
+	Status        string
+	CPURequest    string
+	MemoryRequest string
+}
+
+// This function is used to get the list of all the Nodes with their health, resources and the pods scheduled on them
+func Nodes(log *logrus.Entry) string {
+	clientset := Kconfig
+	nodes, err := clientset.CoreV1().Nodes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find Nodes. Error: " + err.Error())
+		return err.Error()
+	}
+	// Finished pods no longer hold their requests, so they are left out
+	pods, err := clientset.CoreV1().Pods(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{
+		FieldSelector: "status.phase!=" + string(v1.PodSucceeded) + ",status.phase!=" + string(v1.PodFailed),
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	podsByNode := map[string][]v1.Pod{}
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != "" {
+			podsByNode[pod.Spec.NodeName] = append(podsByNode[pod.Spec.NodeName], pod)
+		}
+	}
+
+	var nodeInfo []Node
+	for i := 0; i < len(nodes.Items); i++ {
+		node := nodes.Items[i]
+		info := Node{
+			Name:             node.Name,
+			Unschedulable:    node.Spec.Unschedulable,
+			Capacity:         resourceStrings(node.Status.Capacity),
+			Allocatable:      resourceStrings(node.Status.Allocatable),
+			KubeletVersion:   node.Status.NodeInfo.KubeletVersion,
+			ContainerRuntime: node.Status.NodeInfo.ContainerRuntimeVersion,
+			OSImage:          node.Status.NodeInfo.OSImage,
+			KernelVersion:    node.Status.NodeInfo.KernelVersion,
+			OperatingSystem:  node.Status.NodeInfo.OperatingSystem,
+			Architecture:     node.Status.NodeInfo.Architecture,
+			CreatedAt:        node.CreationTimestamp.String(),
+			UniqueID:         string(node.UID),
+			Labels:           node.Labels,
+		}
+		for _, condition := range node.Status.Conditions {
+			info.Conditions = append(info.Conditions, NodeCondition{
+				Type:    string(condition.Type),
+				Status:  string(condition.Status),
+				Reason:  condition.Reason,
+				Message: condition.Message,
+			})
+			switch condition.Type {
+			case v1.NodeReady:
+				info.Ready = string(condition.Status)
+			case v1.NodeMemoryPressure:
+				info.MemoryPressure = string(condition.Status)
+			case v1.NodeDiskPressure:
+				info.DiskPressure = string(condition.Status)
+			case v1.NodePIDPressure:
+				info.PIDPressure = string(condition.Status)
+			}
+		}
+		for _, taint := range node.Spec.Taints {
+			info.Taints = append(info.Taints, taint.ToString())
+		}
+		for _, address := range node.Status.Addresses {
+			if address.Type == v1.NodeInternalIP {
+				info.InternalIP = address.Address
+				break
+			}
+		}
+
+		total := v1.ResourceList{}
+		for j := range podsByNode[node.Name] {
+			pod := &podsByNode[node.Name][j]
+			requests := podRequests(pod)
+			addResources(total, requests)
+			cpu := requests[v1.ResourceCPU]
+			memory := requests[v1.ResourceMemory]
+			info.Pods = append(info.Pods, NodePod{
+				Namespace:     pod.Namespace,
+				Name:          pod.Name,
+				Status:        string(pod.Status.Phase),
+				CPURequest:    cpu.String(),
+				MemoryRequest: memory.String(),
+			})
+		}
+		info.Requests = resourceStrings(total)
+		nodeInfo = append(nodeInfo, info)
+	}
+
+	node_json, err := json.Marshal(nodeInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(node_json)
+}
+
+// This function works out what a pod asks the scheduler for: the bigger of its containers' sum and
+// its largest init container, plus the pod overhead. It is the same sum kubectl describe node shows.
+func podRequests(pod *v1.Pod) v1.ResourceList {
+	requests := v1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResources(requests, container.Resources.Requests)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		for name, quantity := range container.Resources.Requests {
+			if current, ok := requests[name]; !ok || quantity.Cmp(current) > 0 {
+				requests[name] = quantity.DeepCopy()
+			}
+		}
+	}
+	addResources(requests, pod.Spec.Overhead)
+	return requests
+}
+
+func addResources(total v1.ResourceList, add v1.ResourceList) {
+	for name, quantity := range add {
+		if current, ok := total[name]; ok {
+			current.Add(quantity)
+			total[name] = current
+		} else {
+			total[name] = quantity.DeepCopy()
+		}
+	}
+}
+
+func resourceStrings(list v1.ResourceList) map[string]string {
+	out := map[string]string{}
+	for name, quantity := range list {
+		out[string(name)] = quantity.String()
+	}
+	return out
+}
@@ -0,0 +1,150 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+// These are the kinds the get route knows, by the lower case kind name used in the route
+var objectKinds = map[string]schema.GroupVersionResource{
+	"pod":                      {Version: "v1", Resource: "pods"},
+	"service":                  {Version: "v1", Resource: "services"},
+	"configmap":                {Version: "v1", Resource: "configmaps"},
+	"secret":                   {Version: "v1", Resource: "secrets"},
+	"replicationcontroller":    {Version: "v1", Resource: "replicationcontrollers"},
+	"namespace":                {Version: "v1", Resource: "namespaces"},
+	"node":                     {Version: "v1", Resource: "nodes"},
+	"event":                    {Version: "v1", Resource: "events"},
+	"serviceaccount":           {Version: "v1", Resource: "serviceaccounts"},
+	"persistentvolumeclaim":    {Version: "v1", Resource: "persistentvolumeclaims"},
+	"persistentvolume":         {Version: "v1", Resource: "persistentvolumes"},
+	"deployment":               {Group: "apps", Version: "v1", Resource: "deployments"},
+	"statefulset":              {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"daemonset":                {Group: "apps", Version: "v1", Resource: "daemonsets"},
+	"replicaset":               {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"job":                      {Group: "batch", Version: "v1", Resource: "jobs"},
+	"cronjob":                  {Group: "batch", Version: "v1", Resource: "cronjobs"},
+	"ingress":                  {Group: "networking.k8s.io", Version: "v1", Resource: "ingresses"},
+	"ingressclass":             {Group: "networking.k8s.io", Version: "v1", Resource: "ingressclasses"},
+	"networkpolicy":            {Group: "networking.k8s.io", Version: "v1", Resource: "networkpolicies"},
+	"storageclass":             {Group: "storage.k8s.io", Version: "v1", Resource: "storageclasses"},
+	"horizontalpodautoscaler":  {Group: "autoscaling", Version: "v2", Resource: "horizontalpodautoscalers"},
+	"role":                     {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "roles"},
+	"clusterrole":              {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterroles"},
+	"rolebinding":              {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "rolebindings"},
+	"clusterrolebinding":       {Group: "rbac.authorization.k8s.io", Version: "v1", Resource: "clusterrolebindings"},
+	"customresourcedefinition": {Group: "apiextensions.k8s.io", Version: "v1", Resource: "customresourcedefinitions"},
+}
+
+// This function gets one object of a supported kind in full, as json (default) or yaml.
+// clean strips what the server fills in (status, managedFields, uid, resourceVersion and the like),
+// so the output can be applied again as a manifest.
+func GetObject(kind string, namespace string, name string, output string, clean bool, log *logrus.Entry) string {
+	gvr, ok := objectKinds[strings.ToLower(kind)]
+	if !ok {
+		var kinds []string
+		for k := range objectKinds {
+			kinds = append(kinds, k)
+		}
+		sort.Strings(kinds)
+		log.Error("Unsupported kind: " + kind)
+		return "Unsupported kind: " + kind + ". Supported kinds: " + strings.Join(kinds, ", ")
+	}
+	if output != "" && output != "json" && output != "yaml" {
+		log.Error("Invalid output: " + output)
+		return "Invalid output: " + output + ", it must be json or yaml"
+	}
+	ri, _, err := resourceInterface(gvr.Group, gvr.Version, gvr.Resource, namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	obj, err := ri.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if clean {
+		cleanObject(obj)
+	}
+
+	if output == "yaml" {
+		obj_yaml, err := yaml.Marshal(obj.Object)
+		if err != nil {
+			log.Error(err.Error())
+			return err.Error()
+		}
+		return string(obj_yaml)
+	}
+	obj_json, err := json.MarshalIndent(obj.Object, "", "  ")
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(obj_json)
+}
+
+// This function removes the fields the server populates, the ones a manifest would not carry
+func cleanObject(obj *unstructured.Unstructured) {
+	for _, field := range []string{"managedFields", "uid", "resourceVersion", "generation", "creationTimestamp", "selfLink", "deletionTimestamp", "deletionGracePeriodSeconds", "ownerReferences"} {
+		unstructured.RemoveNestedField(obj.Object, "metadata", field)
+	}
+	unstructured.RemoveNestedField(obj.Object, "status")
+
+	annotations := obj.GetAnnotations()
+	for _, key := range []string{"kubectl.kubernetes.io/last-applied-configuration", "deployment.kubernetes.io/revision"} {
+		delete(annotations, key)
+	}
+	for key := range annotations {
+		if strings.HasPrefix(key, "pv.kubernetes.io/") || strings.HasPrefix(key, "volume.kubernetes.io/") || strings.HasPrefix(key, "volume.beta.kubernetes.io/") {
+			delete(annotations, key)
+		}
+	}
+	if len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj.Object, "metadata", "annotations")
+	} else {
+		obj.SetAnnotations(annotations)
+	}
+
+	// Some kinds get fields allocated or generated on create, they would clash when applied again
+	switch obj.GetKind() {
+	case "Service":
+		// A headless Service keeps clusterIP None, without it the Service would get an address when applied again
+		if clusterIP, _, _ := unstructured.NestedString(obj.Object, "spec", "clusterIP"); clusterIP != v1.ClusterIPNone {
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIP")
+			unstructured.RemoveNestedField(obj.Object, "spec", "clusterIPs")
+		}
+	case "Job":
+		if manual, _, _ := unstructured.NestedBool(obj.Object, "spec", "manualSelector"); manual {
+			break
+		}
+		unstructured.RemoveNestedField(obj.Object, "spec", "selector")
+		for _, label := range []string{"controller-uid", "batch.kubernetes.io/controller-uid", "job-name", "batch.kubernetes.io/job-name"} {
+			unstructured.RemoveNestedField(obj.Object, "spec", "template", "metadata", "labels", label)
+		}
+	case "Pod":
+		unstructured.RemoveNestedField(obj.Object, "spec", "nodeName")
+	case "PersistentVolumeClaim":
+		unstructured.RemoveNestedField(obj.Object, "spec", "volumeName")
+	}
+}
+
+// This function patches one object of a supported kind, see PatchResource
+func PatchObject(kind string, namespace string, name string, request PatchRequest, body []byte, log *logrus.Entry) string {
+	gvr, ok := objectKinds[strings.ToLower(kind)]
+	if !ok {
+		log.Error("Unsupported kind: " + kind)
+		return "Unsupported kind: " + kind
+	}
+	return PatchResource(gvr.Group, gvr.Version, gvr.Resource, namespace, name, request, body, log)
+}
@@ -0,0 +1,193 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+)
+
+func podDetails(pod *v1.Pod, containerDetails bool) Pod {
+	info := Pod{
+		Name:            pod.Name,
+		Status:          podStatus(pod),
+		Phase:           string(pod.Status.Phase),
+		QOSClass:        string(pod.Status.QOSClass),
+		CreatedAt:       pod.CreationTimestamp.String(),
+		UniqueID:        string(pod.GetUID()),
+		NodeName:        pod.Spec.NodeName,
+		IP:              pod.Status.PodIP,
+		ContainersCount: len(pod.Spec.Containers),
+		Labels:          pod.Labels,
+	}
+	ready := 0
+	for _, status := range pod.Status.ContainerStatuses {
+		info.Restarts += status.RestartCount
+		if status.Ready {
+			ready++
+		}
+	}
+	info.Ready = strconv.Itoa(ready) + "/" + strconv.Itoa(len(pod.Spec.Containers))
+	for _, owner := range pod.OwnerReferences {
+		info.Owners = append(info.Owners, owner.Kind+"/"+owner.Name)
+	}
+	for _, condition := range pod.Status.Conditions {
+		info.Conditions = append(info.Conditions, PodCondition{
+			Type:    string(condition.Type),
+			Status:  string(condition.Status),
+			Reason:  condition.Reason,
+			Message: condition.Message,
+		})
+	}
+
+	// Each pod gets only its own containers
+	if containerDetails {
+		for j, container := range pod.Spec.Containers {
+			info.ContainersInfo = append(info.ContainersInfo, containerDetail(j, &container, pod.Status.ContainerStatuses))
+		}
+		for j, container := range pod.Spec.InitContainers {
+			info.InitContainersInfo = append(info.InitContainersInfo, containerDetail(j, &container, pod.Status.InitContainerStatuses))
+		}
+		for j, ec := range pod.Spec.EphemeralContainers {
+			container := v1.Container(ec.EphemeralContainerCommon)
+			info.EphemeralContainersInfo = append(info.EphemeralContainersInfo, containerDetail(j, &container, pod.Status.EphemeralContainerStatuses))
+		}
+	}
+	return info
+}
+
+func containerDetail(index int, container *v1.Container, statuses []v1.ContainerStatus) Container {
+	info := Container{
+		Name:            container.Name,
+		Container:       index,
+		Image:           container.Image,
+		ImagePullPolicy: string(container.ImagePullPolicy),
+		Port:            container.Ports,
+		Requests:        resourceStrings(container.Resources.Requests),
+		Limits:          resourceStrings(container.Resources.Limits),
+		LivenessProbe:   probeString(container.LivenessProbe),
+		ReadinessProbe:  probeString(container.ReadinessProbe),
+		StartupProbe:    probeString(container.StartupProbe),
+	}
+	for _, status := range statuses {
+		if status.Name != container.Name {
+			continue
+		}
+		info.Ready = status.Ready
+		info.Started = status.Started != nil && *status.Started
+		info.RestartCount = status.RestartCount
+		info.State = containerState(status.State)
+		info.LastState = containerState(status.LastTerminationState)
+		// The status carries the image that is actually running, with its digest
+		if status.ImageID != "" {
+			info.Image = status.Image
+		}
+	}
+	return info
+}
+
+func containerState(state v1.ContainerState) ContainerState {
+	switch {
+	case state.Running != nil:
+		return ContainerState{State: "Running", StartedAt: state.Running.StartedAt.String()}
+	case state.Waiting != nil:
+		return ContainerState{State: "Waiting", Reason: state.Waiting.Reason, Message: state.Waiting.Message}
+	case state.Terminated != nil:
+		return ContainerState{
+			State:      "Terminated",
+			Reason:     state.Terminated.Reason,
+			Message:    state.Terminated.Message,
+			ExitCode:   state.Terminated.ExitCode,
+			StartedAt:  state.Terminated.StartedAt.String(),
+			FinishedAt: state.Terminated.FinishedAt.String(),
+		}
+	}
+	return ContainerState{}
+}
+
+// This function describes a probe the way kubectl describe does, e.g. "http-get :8080/healthz delay=5s timeout=1s period=10s #success=1 #failure=3"
+func probeString(probe *v1.Probe) string {
+	if probe == nil {
+		return ""
+	}
+	var handler string
+	switch {
+	case probe.HTTPGet != nil:
+		handler = "http-get " + strings.ToLower(string(probe.HTTPGet.Scheme)) + "://" + probe.HTTPGet.Host + ":" + probe.HTTPGet.Port.String() + probe.HTTPGet.Path
+	case probe.TCPSocket != nil:
+		handler = "tcp-socket " + probe.TCPSocket.Host + ":" + probe.TCPSocket.Port.String()
+	case probe.GRPC != nil:
+		handler = "grpc <pod>:" + strconv.Itoa(int(probe.GRPC.Port))
+		if probe.GRPC.Service != nil {
+			handler += " " + *probe.GRPC.Service
+		}
+	case probe.Exec != nil:
+		handler = "exec [" + strings.Join(probe.Exec.Command, " ") + "]"
+	default:
+		handler = "unknown"
+	}
+	return handler +
+		" delay=" + strconv.Itoa(int(probe.InitialDelaySeconds)) + "s" +
+		" timeout=" + strconv.Itoa(int(probe.TimeoutSeconds)) + "s" +
+		" period=" + strconv.Itoa(int(probe.PeriodSeconds)) + "s" +
+		" #success=" + strconv.Itoa(int(probe.SuccessThreshold)) +
+		" #failure=" + strconv.Itoa(int(probe.FailureThreshold))
+}
+
+// This function works out the status kubectl get pods shows, so a crashing container reads CrashLoopBackOff
+// instead of the Running phase
+func podStatus(pod *v1.Pod) string {
+	reason := string(pod.Status.Phase)
+	if pod.Status.Reason != "" {
+		reason = pod.Status.Reason
+	}
+
+	initializing := false
+	for i, status := range pod.Status.InitContainerStatuses {
+		switch {
+		case status.State.Terminated != nil && status.State.Terminated.ExitCode == 0:
+			continue
+		case status.State.Terminated != nil:
+			if status.State.Terminated.Reason != "" {
+				reason = "Init:" + status.State.Terminated.Reason
+			} else {
+				reason = "Init:ExitCode:" + strconv.Itoa(int(status.State.Terminated.ExitCode))
+			}
+		case status.State.Waiting != nil && status.State.Waiting.Reason != "" && status.State.Waiting.Reason != "PodInitializing":
+			reason = "Init:" + status.State.Waiting.Reason
+		default:
+			reason = "Init:" + strconv.Itoa(i) + "/" + strconv.Itoa(len(pod.Spec.InitContainers))
+		}
+		initializing = true
+		break
+	}
+
+	if !initializing {
+		running := false
+		for i := len(pod.Status.ContainerStatuses) - 1; i >= 0; i-- {
+			status := pod.Status.ContainerStatuses[i]
+			switch {
+			case status.State.Waiting != nil && status.State.Waiting.Reason != "":
+				reason = status.State.Waiting.Reason
+			case status.State.Terminated != nil && status.State.Terminated.Reason != "":
+				reason = status.State.Terminated.Reason
+			case status.State.Terminated != nil:
+				reason = "ExitCode:" + strconv.Itoa(int(status.State.Terminated.ExitCode))
+			case status.Ready && status.State.Running != nil:
+				running = true
+			}
+		}
+		// A pod with one container done and another still running is Running
+		if reason == "Completed" && running {
+			reason = "Running"
+		}
+	}
+
+	if pod.DeletionTimestamp != nil {
+		if pod.Status.Reason == "NodeLost" {
+			return "Unknown"
+		}
+		return "Terminating"
+	}
+	return reason
+}
@@ -0,0 +1,481 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	authorizationv1 "k8s.io/api/authorization/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// These are all the Structs that are used for RBAC
+type Serviceaccount struct {
+	Name             string
+	Secrets          []string
+	ImagePullSecrets []string
+	Bindings         []string
+	CreatedAt        string
+	UniqueID         string
+	Labels           map[string]string
+}
+
+type Role struct {
+	Name        string
+	Namespace   string
+	Rules       []PolicyRule
+	AggregateOf []string
+	CreatedAt   string
+	UniqueID    string
+	Labels      map[string]string
+}
+
+type PolicyRule struct {
+	Verbs           []string
+	APIGroups       []string
+	Resources       []string
+	ResourceNames   []string
+	NonResourceURLs []string
+}
+
+type Rolebinding struct {
+	Name      string
+	Namespace string
+	Role      string
+	Subjects  []string
+	CreatedAt string
+	UniqueID  string
+	Labels    map[string]string
+}
+
+// RbacSubject is one answer to "who can": a subject and the binding and role that give it the access
+type RbacSubject struct {
+	Subject string
+	Binding string
+	Role    string
+}
+
+type AccessReview struct {
+	Subject   string
+	Allowed   bool
+	Denied    bool
+	Reason    string
+	Error     string
+	Attribute string
+}
+
+// This function is used to get the list of all the ServiceAccounts in the namespace with the bindings naming them
+func ServiceAccounts(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	accounts, err := clientset.CoreV1().ServiceAccounts(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find ServiceAccounts. Error: " + err.Error())
+		return err.Error()
+	}
+	bindings, err := bindingsBySubject()
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var accountInfo []Serviceaccount
+	for i := 0; i < len(accounts.Items); i++ {
+		account := accounts.Items[i]
+		info := Serviceaccount{
+			Name:      account.Name,
+			Bindings:  bindings["ServiceAccount:"+AgentNamespace+"/"+account.Name],
+			CreatedAt: account.CreationTimestamp.String(),
+			UniqueID:  string(account.UID),
+			Labels:    account.Labels,
+		}
+		for _, secret := range account.Secrets {
+			info.Secrets = append(info.Secrets, secret.Name)
+		}
+		for _, secret := range account.ImagePullSecrets {
+			info.ImagePullSecrets = append(info.ImagePullSecrets, secret.Name)
+		}
+		accountInfo = append(accountInfo, info)
+	}
+	account_json, err := json.Marshal(accountInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(account_json)
+}
+
+// This function maps every subject to the RoleBindings and ClusterRoleBindings naming it. RoleBindings of all
+// namespaces are read, as one in any namespace can grant a ServiceAccount access there.
+func bindingsBySubject() (map[string][]string, error) {
+	clientset := Kconfig
+	out := map[string][]string{}
+	roleBindings, err := clientset.RbacV1().RoleBindings(metav1.NamespaceAll).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range roleBindings.Items {
+		for _, subject := range binding.Subjects {
+			key := subjectString(subject, binding.Namespace)
+			out[key] = append(out[key], "RoleBinding/"+binding.Namespace+"/"+binding.Name)
+		}
+	}
+	clusterBindings, err := clientset.RbacV1().ClusterRoleBindings().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, binding := range clusterBindings.Items {
+		for _, subject := range binding.Subjects {
+			key := subjectString(subject, "")
+			out[key] = append(out[key], "ClusterRoleBinding/"+binding.Name)
+		}
+	}
+	return out, nil
+}
+
+// A ServiceAccount subject of a RoleBinding without a namespace is in the binding's namespace
+func subjectString(subject rbacv1.Subject, bindingNamespace string) string {
+	if subject.Kind == rbacv1.ServiceAccountKind {
+		namespace := subject.Namespace
+		if namespace == "" {
+			namespace = bindingNamespace
+		}
+		return subject.Kind + ":" + namespace + "/" + subject.Name
+	}
+	return subject.Kind + ":" + subject.Name
+}
+
+func policyRules(rules []rbacv1.PolicyRule) []PolicyRule {
+	var out []PolicyRule
+	for _, rule := range rules {
+		out = append(out, PolicyRule{
+			Verbs:           rule.Verbs,
+			APIGroups:       rule.APIGroups,
+			Resources:       rule.Resources,
+			ResourceNames:   rule.ResourceNames,
+			NonResourceURLs: rule.NonResourceURLs,
+		})
+	}
+	return out
+}
+
+// This function is used to get the list of all the Roles in the namespace
+func Roles(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	roles, err := clientset.RbacV1().Roles(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find Roles. Error: " + err.Error())
+		return err.Error()
+	}
+	var roleInfo []Role
+	for i := 0; i < len(roles.Items); i++ {
+		role := roles.Items[i]
+		roleInfo = append(roleInfo, Role{
+			Name:      role.Name,
+			Namespace: role.Namespace,
+			Rules:     policyRules(role.Rules),
+			CreatedAt: role.CreationTimestamp.String(),
+			UniqueID:  string(role.UID),
+			Labels:    role.Labels,
+		})
+	}
+	role_json, err := json.Marshal(roleInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(role_json)
+}
+
+// This function is used to get the list of all the ClusterRoles in the cluster
+func ClusterRoles(log *logrus.Entry) string {
+	clientset := Kconfig
+	roles, err := clientset.RbacV1().ClusterRoles().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find ClusterRoles. Error: " + err.Error())
+		return err.Error()
+	}
+	var roleInfo []Role
+	for i := 0; i < len(roles.Items); i++ {
+		role := roles.Items[i]
+		info := Role{
+			Name:      role.Name,
+			Rules:     policyRules(role.Rules),
+			CreatedAt: role.CreationTimestamp.String(),
+			UniqueID:  string(role.UID),
+			Labels:    role.Labels,
+		}
+		// The rules of an aggregated ClusterRole are filled in by the controller, the selectors say where they come from
+		if role.AggregationRule != nil {
+			for _, selector := range role.AggregationRule.ClusterRoleSelectors {
+				info.AggregateOf = append(info.AggregateOf, metav1.FormatLabelSelector(&selector))
+			}
+		}
+		roleInfo = append(roleInfo, info)
+	}
+	role_json, err := json.Marshal(roleInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(role_json)
+}
+
+// This function is used to get the list of all the RoleBindings in the namespace
+func RoleBindings(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	bindings, err := clientset.RbacV1().RoleBindings(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find RoleBindings. Error: " + err.Error())
+		return err.Error()
+	}
+	var bindingInfo []Rolebinding
+	for i := 0; i < len(bindings.Items); i++ {
+		binding := bindings.Items[i]
+		info := Rolebinding{
+			Name:      binding.Name,
+			Namespace: binding.Namespace,
+			Role:      binding.RoleRef.Kind + "/" + binding.RoleRef.Name,
+			CreatedAt: binding.CreationTimestamp.String(),
+			UniqueID:  string(binding.UID),
+			Labels:    binding.Labels,
+		}
+		for _, subject := range binding.Subjects {
+			info.Subjects = append(info.Subjects, subjectString(subject, binding.Namespace))
+		}
+		bindingInfo = append(bindingInfo, info)
+	}
+	binding_json, err := json.Marshal(bindingInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(binding_json)
+}
+
+// This function is used to get the list of all the ClusterRoleBindings in the cluster
+func ClusterRoleBindings(log *logrus.Entry) string {
+	clientset := Kconfig
+	bindings, err := clientset.RbacV1().ClusterRoleBindings().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find ClusterRoleBindings. Error: " + err.Error())
+		return err.Error()
+	}
+	var bindingInfo []Rolebinding
+	for i := 0; i < len(bindings.Items); i++ {
+		binding := bindings.Items[i]
+		info := Rolebinding{
+			Name:      binding.Name,
+			Role:      binding.RoleRef.Kind + "/" + binding.RoleRef.Name,
+			CreatedAt: binding.CreationTimestamp.String(),
+			UniqueID:  string(binding.UID),
+			Labels:    binding.Labels,
+		}
+		for _, subject := range binding.Subjects {
+			info.Subjects = append(info.Subjects, subjectString(subject, ""))
+		}
+		bindingInfo = append(bindingInfo, info)
+	}
+	binding_json, err := json.Marshal(bindingInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(binding_json)
+}
+
+// This function answers "who can <verb> <resource> in <namespace>" from the bindings: every ClusterRoleBinding
+// and the RoleBindings of the namespace whose role has a matching rule. resource can carry a subresource (pods/log)
+// and group is the API group ("" for core). Leaving namespace out only looks at ClusterRoleBindings.
+// Access given by other authorizers (like node or webhook) is not seen here, /canI asks the API server instead.
+func WhoCan(verb string, resource string, group string, namespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if verb == "" || resource == "" {
+		log.Error("verb and resource are required")
+		return "verb and resource are required"
+	}
+	ctx := context.Background()
+	clusterRoles, err := clientset.RbacV1().ClusterRoles().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	clusterRules := map[string][]rbacv1.PolicyRule{}
+	for _, role := range clusterRoles.Items {
+		clusterRules[role.Name] = role.Rules
+	}
+
+	var subjects []RbacSubject
+	clusterBindings, err := clientset.RbacV1().ClusterRoleBindings().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	for _, binding := range clusterBindings.Items {
+		if !rulesAllow(clusterRules[binding.RoleRef.Name], verb, resource, group) {
+			continue
+		}
+		for _, subject := range binding.Subjects {
+			subjects = append(subjects, RbacSubject{
+				Subject: subjectString(subject, ""),
+				Binding: "ClusterRoleBinding/" + binding.Name,
+				Role:    "ClusterRole/" + binding.RoleRef.Name,
+			})
+		}
+	}
+
+	if namespace != "" {
+		roles, err := clientset.RbacV1().Roles(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Error(err.Error())
+			return err.Error()
+		}
+		roleRules := map[string][]rbacv1.PolicyRule{}
+		for _, role := range roles.Items {
+			roleRules[role.Name] = role.Rules
+		}
+		bindings, err := clientset.RbacV1().RoleBindings(namespace).List(ctx, metav1.ListOptions{})
+		if err != nil {
+			log.Error(err.Error())
+			return err.Error()
+		}
+		for _, binding := range bindings.Items {
+			// A RoleBinding can point at a ClusterRole too, it then only gives access in its own namespace
+			rules := roleRules[binding.RoleRef.Name]
+			if binding.RoleRef.Kind == "ClusterRole" {
+				rules = clusterRules[binding.RoleRef.Name]
+			}
+			if !rulesAllow(rules, verb, resource, group) {
+				continue
+			}
+			for _, subject := range binding.Subjects {
+				subjects = append(subjects, RbacSubject{
+					Subject: subjectString(subject, binding.Namespace),
+					Binding: "RoleBinding/" + binding.Namespace + "/" + binding.Name,
+					Role:    binding.RoleRef.Kind + "/" + binding.RoleRef.Name,
+				})
+			}
+		}
+	}
+	sort.SliceStable(subjects, func(i, j int) bool { return subjects[i].Subject < subjects[j].Subject })
+
+	subject_json, err := json.Marshal(subjects)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(subject_json)
+}
+
+// Rules limited to resourceNames still count, as the subject can do it to some objects
+func rulesAllow(rules []rbacv1.PolicyRule, verb string, resource string, group string) bool {
+	for _, rule := range rules {
+		if ruleMatches(rule.Verbs, verb) && ruleMatches(rule.APIGroups, group) && resourceMatches(rule.Resources, resource) {
+			return true
+		}
+	}
+	return false
+}
+
+func ruleMatches(values []string, want string) bool {
+	for _, value := range values {
+		if value == rbacv1.VerbAll || value == want {
+			return true
+		}
+	}
+	return false
+}
+
+// "*/scale" in a rule matches the scale subresource of every resource
+func resourceMatches(values []string, want string) bool {
+	if ruleMatches(values, want) {
+		return true
+	}
+	if i := strings.Index(want, "/"); i >= 0 {
+		return ruleMatches(values, "*"+want[i:])
+	}
+	return false
+}
+
+// This function asks the API server whether a subject can do something with a SubjectAccessReview, so every
+// authorizer and aggregated role is taken into account. kind is User, Group or ServiceAccount; a ServiceAccount
+// subject is written namespace/name. resource can carry a subresource (pods/log).
+func CanI(kind string, subject string, verb string, resource string, group string, namespace string, name string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if subject == "" || verb == "" || resource == "" {
+		log.Error("subject, verb and resource are required")
+		return "subject, verb and resource are required"
+	}
+	spec := authorizationv1.SubjectAccessReviewSpec{}
+	switch kind {
+	case "User", "":
+		kind = "User"
+		spec.User = subject
+	case "Group":
+		spec.Groups = []string{subject}
+	case "ServiceAccount":
+		parts := strings.SplitN(subject, "/", 2)
+		if len(parts) != 2 {
+			log.Error("ServiceAccount subject must be namespace/name")
+			return "ServiceAccount subject must be namespace/name"
+		}
+		// These are the user and groups a ServiceAccount token authenticates as
+		spec.User = "system:serviceaccount:" + parts[0] + ":" + parts[1]
+		spec.Groups = []string{"system:serviceaccounts", "system:serviceaccounts:" + parts[0], "system:authenticated"}
+	default:
+		log.Error("kind must be User, Group or ServiceAccount")
+		return "kind must be User, Group or ServiceAccount"
+	}
+	attributes := &authorizationv1.ResourceAttributes{Namespace: namespace, Verb: verb, Group: group, Name: name, Resource: resource}
+	if i := strings.Index(resource, "/"); i >= 0 {
+		attributes.Resource = resource[:i]
+		attributes.Subresource = resource[i+1:]
+	}
+	spec.ResourceAttributes = attributes
+
+	review, err := clientset.AuthorizationV1().SubjectAccessReviews().Create(context.Background(), &authorizationv1.SubjectAccessReview{Spec: spec}, metav1.CreateOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	attribute := verb + " " + resource
+	if group != "" {
+		attribute += "." + group
+	}
+	if name != "" {
+		attribute += " " + name
+	}
+	if namespace != "" {
+		attribute += " in " + namespace
+	}
+	review_json, err := json.Marshal(AccessReview{
+		Subject:   kind + ":" + subject,
+		Allowed:   review.Status.Allowed,
+		Denied:    review.Status.Denied,
+		Reason:    review.Status.Reason,
+		Error:     review.Status.EvaluationError,
+		Attribute: attribute,
+	})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(review_json)
+}
@@ -0,0 +1,182 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/sirupsen/logrus"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/dynamic"
+)
+
+// This function finds the resource through discovery and gives back the dynamic client for it, in the namespace when
+// the resource is namespaced. group is "core" (or empty) for the core API, resource is the plural, singular or short name.
+func resourceInterface(group string, version string, resource string, namespace string) (dynamic.ResourceInterface, *meta.RESTMapping, error) {
+	if group == "core" {
+		group = ""
+	}
+	gvr := schema.GroupVersionResource{Group: group, Version: version, Resource: resource}
+	mapping, err := restMapping(gvr)
+	if err != nil {
+		// A CRD created after the cache was filled is only found once discovery is read again
+		Mapper.Reset()
+		mapping, err = restMapping(gvr)
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+	if mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		if namespace == "" {
+			namespace = "default"
+		}
+		return Dynamic.Resource(mapping.Resource).Namespace(namespace), mapping, nil
+	}
+	return Dynamic.Resource(mapping.Resource), mapping, nil
+}
+
+func restMapping(gvr schema.GroupVersionResource) (*meta.RESTMapping, error) {
+	gvk, err := Mapper.KindFor(gvr)
+	if err != nil {
+		return nil, err
+	}
+	return Mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+}
+
+// This function lists the objects of any resource the cluster serves, as unstructured JSON.
+// allNamespaces lists a namespaced resource across the cluster, namespace is ignored for cluster-scoped ones.
+func ListResources(group string, version string, resource string, namespace string, allNamespaces bool, labelSelector string, fieldSelector string, log *logrus.Entry) string {
+	if allNamespaces {
+		namespace = metav1.NamespaceAll
+	}
+	ri, mapping, err := resourceInterface(group, version, resource, namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if allNamespaces && mapping.Scope.Name() == meta.RESTScopeNameNamespace {
+		ri = Dynamic.Resource(mapping.Resource)
+	}
+	list, err := ri.List(context.Background(), metav1.ListOptions{LabelSelector: labelSelector, FieldSelector: fieldSelector})
+	if err != nil {
+		log.Error("Unable to find " + mapping.Resource.Resource + ". Error: " + err.Error())
+		return err.Error()
+	}
+	list_json, err := json.Marshal(list)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(list_json)
+}
+
+// This function gets one object of any resource the cluster serves, as unstructured JSON
+func GetResource(group string, version string, resource string, namespace string, name string, log *logrus.Entry) string {
+	ri, _, err := resourceInterface(group, version, resource, namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	obj, err := ri.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	obj_json, err := json.Marshal(obj)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(obj_json)
+}
+
+// This function Deletes one object of any resource the cluster serves, its dependents are deleted in the background
+func DeleteResource(group string, version string, resource string, namespace string, name string, log *logrus.Entry) string {
+	ri, mapping, err := resourceInterface(group, version, resource, namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	propagation := metav1.DeletePropagationBackground
+	err = ri.Delete(context.Background(), name, metav1.DeleteOptions{PropagationPolicy: &propagation})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info(mapping.GroupVersionKind.Kind + ": " + name + " Deleted!")
+	return mapping.GroupVersionKind.Kind + ": " + name + " Deleted!"
+}
+
+// PatchRequest says how a patch is applied. Type is merge (default), json, strategic or apply (server-side apply),
+// FieldManager names who makes the change (kube-ez by default), DryRun only shows the result and Force takes over
+// fields owned by another manager on apply.
+type PatchRequest struct {
+	Type         string
+	FieldManager string
+	DryRun       bool
+	Force        bool
+}
+
+// This function patches one object of any resource the cluster serves and gives back the resulting object.
+// The body can be JSON or YAML; strategic merge only works for the built-in kinds.
+func PatchResource(group string, version string, resource string, namespace string, name string, request PatchRequest, body []byte, log *logrus.Entry) string {
+	var pt types.PatchType
+	switch request.Type {
+	case "merge", "":
+		pt = types.MergePatchType
+	case "json":
+		pt = types.JSONPatchType
+	case "strategic":
+		pt = types.StrategicMergePatchType
+	case "apply":
+		pt = types.ApplyPatchType
+	default:
+		log.Error("Invalid patch type: " + request.Type)
+		return "Invalid patch type: " + request.Type + ", it must be merge, json, strategic or apply"
+	}
+	// Apply takes YAML as it is, the other patch types have to be JSON
+	if pt != types.ApplyPatchType {
+		converted, err := yaml.ToJSON(body)
+		if err != nil {
+			log.Error("Invalid patch. Error: " + err.Error())
+			return "Invalid patch. Error: " + err.Error()
+		}
+		body = converted
+	}
+	options := metav1.PatchOptions{FieldManager: request.FieldManager}
+	if options.FieldManager == "" {
+		options.FieldManager = "kube-ez"
+	}
+	if request.DryRun {
+		options.DryRun = []string{metav1.DryRunAll}
+	}
+	if pt == types.ApplyPatchType {
+		options.Force = &request.Force
+	}
+
+	ri, mapping, err := resourceInterface(group, version, resource, namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	obj, err := ri.Patch(context.Background(), name, pt, body, options)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if request.DryRun {
+		log.Info(mapping.GroupVersionKind.Kind + ": " + name + " Patched (dry run)!")
+	} else {
+		log.Info(mapping.GroupVersionKind.Kind + ": " + name + " Patched!")
+	}
+	obj_json, err := json.Marshal(obj)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(obj_json)
+}
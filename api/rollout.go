@@ -0,0 +1,442 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gopkg.in/yaml.v2"
+
+	"k8-api/operations"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// DeploymentRevision is one entry of a Deployment's rollout history
+type DeploymentRevision struct {
+	Revision    int64
+	ReplicaSet  string
+	ChangeCause string
+	Images      map[string]string
+	CreatedAt   string
+	Current     bool
+	Diff        []string
+	Template    string `json:",omitempty"`
+}
+
+// This function restarts every pod of the Deployment with a rolling update, like kubectl rollout restart
+func RestartDeployment(ctx context.Context, namespace string, deployment string, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	d, err := Kconfig.AppsV1().Deployments(namespace).Get(ctx, deployment, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if d.Spec.Paused {
+		log.Error("Deployment: " + deployment + " is paused, resume it first")
+		return "Deployment: " + deployment + " is paused, resume it first"
+	}
+	_, err = Kconfig.AppsV1().Deployments(namespace).Patch(ctx, deployment, types.StrategicMergePatchType, restartPatch(), metav1.PatchOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Deployment: " + deployment + " Restarted!")
+	return "Deployment: " + deployment + " Restarted!"
+}
+
+// This function pauses a Deployment, so template changes pile up without being rolled out, or resumes it
+func PauseDeployment(ctx context.Context, namespace string, deployment string, pause bool, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	patch := []byte(fmt.Sprintf(`{"spec":{"paused":%t}}`, pause))
+	_, err := Kconfig.AppsV1().Deployments(namespace).Patch(ctx, deployment, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if pause {
+		log.Info("Deployment: " + deployment + " Paused!")
+		return "Deployment: " + deployment + " Paused!"
+	}
+	log.Info("Deployment: " + deployment + " Resumed!")
+	return "Deployment: " + deployment + " Resumed!"
+}
+
+// This function gives the rollout history of a Deployment from its ReplicaSets, newest first. Every revision carries
+// the diff of its pod template against the revision before it, and asking for one revision also gives its full template.
+func DeploymentHistory(ctx context.Context, namespace string, deployment string, revision string, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	d, replicaSets, err := deploymentAndReplicaSets(ctx, namespace, deployment)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var want int64
+	if revision != "" {
+		want, err = strconv.ParseInt(revision, 10, 64)
+		if err != nil {
+			log.Error("Invalid revision: " + revision)
+			return "Invalid revision: " + revision
+		}
+	}
+
+	var history []DeploymentRevision
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		entry := DeploymentRevision{
+			Revision:    replicaSetRevision(rs),
+			ReplicaSet:  rs.Name,
+			ChangeCause: rs.Annotations[changeCauseAnnotation],
+			Images:      containerImages(&rs.Spec.Template.Spec),
+			CreatedAt:   rs.CreationTimestamp.String(),
+			Current:     templatesEqual(&rs.Spec.Template, &d.Spec.Template),
+		}
+		// The ReplicaSets are sorted newest first, so the one before this revision is the next one
+		previous := ""
+		if i+1 < len(replicaSets) {
+			previous = templateYAML(&replicaSets[i+1].Spec.Template)
+		}
+		current := templateYAML(&rs.Spec.Template)
+		entry.Diff = lineDiff(previous, current)
+		if want != 0 {
+			if entry.Revision != want {
+				continue
+			}
+			entry.Template = current
+		}
+		history = append(history, entry)
+	}
+	if want != 0 && len(history) == 0 {
+		log.Error("Deployment: " + deployment + " has no revision " + revision)
+		return "Deployment: " + deployment + " has no revision " + revision
+	}
+	history_json, err := json.Marshal(history)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(history_json)
+}
+
+// This function rolls a Deployment back to the pod template of an earlier revision, the one before the current
+// when toRevision is empty. The rollback is a new revision, as with kubectl rollout undo.
+func UndoDeployment(ctx context.Context, namespace string, deployment string, toRevision string, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	d, replicaSets, err := deploymentAndReplicaSets(ctx, namespace, deployment)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if d.Spec.Paused {
+		log.Error("Deployment: " + deployment + " is paused, resume it before rolling back")
+		return "Deployment: " + deployment + " is paused, resume it before rolling back"
+	}
+	var want int64
+	if toRevision != "" {
+		want, err = strconv.ParseInt(toRevision, 10, 64)
+		if err != nil || want < 1 {
+			log.Error("Invalid toRevision: " + toRevision)
+			return "Invalid toRevision: " + toRevision
+		}
+	}
+
+	var target *appsv1.ReplicaSet
+	for i := range replicaSets {
+		rs := &replicaSets[i]
+		if want != 0 && replicaSetRevision(rs) == want {
+			target = rs
+			break
+		}
+		// Without a revision it is the newest one that is not running now
+		if want == 0 && !templatesEqual(&rs.Spec.Template, &d.Spec.Template) {
+			target = rs
+			break
+		}
+	}
+	if target == nil {
+		if want != 0 {
+			log.Error("Deployment: " + deployment + " has no revision " + toRevision)
+			return "Deployment: " + deployment + " has no revision " + toRevision
+		}
+		log.Error("Deployment: " + deployment + " has no previous revision")
+		return "Deployment: " + deployment + " has no previous revision"
+	}
+	revision := strconv.FormatInt(replicaSetRevision(target), 10)
+	if templatesEqual(&target.Spec.Template, &d.Spec.Template) {
+		log.Info("Deployment: " + deployment + " already runs revision " + revision)
+		return "Deployment: " + deployment + " already runs revision " + revision + ", skipped rollback"
+	}
+
+	template := target.Spec.Template.DeepCopy()
+	delete(template.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	patch := []map[string]interface{}{{"op": "replace", "path": "/spec/template", "value": template}}
+	cause := "rollback to revision " + revision
+	if target.Annotations[changeCauseAnnotation] != "" {
+		cause += ": " + target.Annotations[changeCauseAnnotation]
+	}
+	if d.Annotations == nil {
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/metadata/annotations", "value": map[string]string{changeCauseAnnotation: cause}})
+	} else {
+		patch = append(patch, map[string]interface{}{"op": "add", "path": "/metadata/annotations/" + strings.ReplaceAll(changeCauseAnnotation, "/", "~1"), "value": cause})
+	}
+	body, err := json.Marshal(patch)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	_, err = Kconfig.AppsV1().Deployments(namespace).Patch(ctx, deployment, types.JSONPatchType, body, metav1.PatchOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Deployment: " + deployment + " Rolled back to revision " + revision + "!")
+	return "Deployment: " + deployment + " Rolled back to revision " + revision + "!"
+}
+
+// This function gives the rollout status of a Deployment. With wait it runs as an operation that ends once the
+// rollout is complete, or fails once it stalls (its progress deadline is exceeded) or after timeout.
+func DeploymentRolloutStatus(ctx context.Context, namespace string, deployment string, wait bool, timeout string, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	limit := 10 * time.Minute
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			log.Error("Invalid timeout: " + timeout)
+			return "Invalid timeout: " + timeout
+		}
+		limit = d
+	}
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+
+	var status RolloutStatus
+	var err error
+	if wait {
+		status, err = waitForRollout(ctx, "Deployment", namespace, deployment)
+	} else {
+		status, _, err = workloadRolloutStatus(ctx, "Deployment", namespace, deployment)
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	status_json, err := json.Marshal(status)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(status_json)
+}
+
+// This function reads the rollout status of a Deployment, StatefulSet or DaemonSet, the second value is true once it stalled
+func workloadRolloutStatus(ctx context.Context, kind string, namespace string, name string) (RolloutStatus, bool, error) {
+	switch kind {
+	case "Deployment":
+		d, err := Kconfig.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return RolloutStatus{}, false, err
+		}
+		status, stalled := deploymentRolloutStatus(d)
+		return status, stalled, nil
+	case "StatefulSet":
+		s, err := Kconfig.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return RolloutStatus{}, false, err
+		}
+		return statefulsetRolloutStatus(s), false, nil
+	case "DaemonSet":
+		ds, err := Kconfig.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return RolloutStatus{}, false, err
+		}
+		return daemonsetRolloutStatus(ds), false, nil
+	}
+	return RolloutStatus{}, false, fmt.Errorf("rollout status is not available for %s", kind)
+}
+
+// This function polls the rollout until it is done, and fails once it stalls or ctx is done.
+// It reports every new status as progress of the operation it runs in.
+func waitForRollout(ctx context.Context, kind string, namespace string, name string) (RolloutStatus, error) {
+	for {
+		status, stalled, err := workloadRolloutStatus(ctx, kind, namespace, name)
+		if err != nil || status.Done {
+			return status, err
+		}
+		if stalled {
+			return status, errors.New(status.Message)
+		}
+		operations.Progress(ctx, status.Message)
+		select {
+		case <-ctx.Done():
+			return status, fmt.Errorf("%s: %s not rolled out in time. %s", kind, name, status.Message)
+		case <-time.After(2 * time.Second):
+		}
+	}
+}
+
+// This function reads the rollout the way kubectl rollout status does, the second value is true once it stalled
+func deploymentRolloutStatus(d *appsv1.Deployment) (RolloutStatus, bool) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return RolloutStatus{Message: "Waiting for deployment spec update to be observed"}, false
+	}
+	for _, condition := range d.Status.Conditions {
+		if condition.Type == appsv1.DeploymentProgressing && condition.Reason == "ProgressDeadlineExceeded" {
+			return RolloutStatus{Message: "Deployment " + d.Name + " exceeded its progress deadline"}, true
+		}
+	}
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	updated := d.Status.UpdatedReplicas
+	prefix := "Waiting for deployment " + d.Name + " rollout to finish: "
+	switch {
+	case updated < replicas:
+		return RolloutStatus{Message: prefix + strconv.Itoa(int(updated)) + " out of " + strconv.Itoa(int(replicas)) + " new replicas have been updated"}, false
+	case d.Status.Replicas > updated:
+		return RolloutStatus{Message: prefix + strconv.Itoa(int(d.Status.Replicas-updated)) + " old replicas are pending termination"}, false
+	case d.Status.AvailableReplicas < updated:
+		return RolloutStatus{Message: prefix + strconv.Itoa(int(d.Status.AvailableReplicas)) + " of " + strconv.Itoa(int(updated)) + " updated replicas are available"}, false
+	}
+	return RolloutStatus{Done: true, Message: "Deployment " + d.Name + " successfully rolled out"}, false
+}
+
+// This function reads the rollout of a StatefulSet the way kubectl rollout status does
+func statefulsetRolloutStatus(s *appsv1.StatefulSet) RolloutStatus {
+	if s.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return RolloutStatus{Done: true, Message: "Rollout status is only available for the RollingUpdate strategy"}
+	}
+	if s.Status.ObservedGeneration == 0 || s.Generation > s.Status.ObservedGeneration {
+		return RolloutStatus{Message: "Waiting for statefulset spec update to be observed"}
+	}
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	prefix := "Waiting for statefulset " + s.Name + " rollout to finish: "
+	if s.Status.ReadyReplicas < replicas {
+		return RolloutStatus{Message: "Waiting for " + strconv.Itoa(int(replicas-s.Status.ReadyReplicas)) + " pods to be ready"}
+	}
+	// With a partition only the pods from that ordinal up are updated
+	if ru := s.Spec.UpdateStrategy.RollingUpdate; ru != nil && ru.Partition != nil && *ru.Partition > 0 {
+		if s.Status.UpdatedReplicas < replicas-*ru.Partition {
+			return RolloutStatus{Message: prefix + strconv.Itoa(int(s.Status.UpdatedReplicas)) + " of " + strconv.Itoa(int(replicas-*ru.Partition)) + " updated"}
+		}
+		return RolloutStatus{Done: true, Message: "Partitioned roll out complete: " + strconv.Itoa(int(s.Status.UpdatedReplicas)) + " new pods have been updated"}
+	}
+	if s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return RolloutStatus{Message: prefix + strconv.Itoa(int(s.Status.UpdatedReplicas)) + " pods at revision " + s.Status.UpdateRevision}
+	}
+	return RolloutStatus{Done: true, Message: "Statefulset " + s.Name + " rolling update complete " + strconv.Itoa(int(s.Status.CurrentReplicas)) + " pods at revision " + s.Status.CurrentRevision}
+}
+
+func deploymentAndReplicaSets(ctx context.Context, namespace string, deployment string) (*appsv1.Deployment, []appsv1.ReplicaSet, error) {
+	d, err := Kconfig.AppsV1().Deployments(namespace).Get(ctx, deployment, metav1.GetOptions{})
+	if err != nil {
+		return nil, nil, err
+	}
+	replicaSets, err := replicaSetsByOwner(namespace)
+	if err != nil {
+		return nil, nil, err
+	}
+	return d, replicaSets[d.UID], nil
+}
+
+// A ReplicaSet's template has the pod-template-hash label on top of the Deployment's, it is left out to compare them
+func templatesEqual(rs *v1.PodTemplateSpec, deployment *v1.PodTemplateSpec) bool {
+	a := rs.DeepCopy()
+	b := deployment.DeepCopy()
+	delete(a.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	delete(b.Labels, appsv1.DefaultDeploymentUniqueLabelKey)
+	return apiequality.Semantic.DeepEqual(a, b)
+}
+
+func templateYAML(template *v1.PodTemplateSpec) string {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(template)
+	if err != nil {
+		return ""
+	}
+	if metadata, ok := obj["metadata"].(map[string]interface{}); ok {
+		delete(metadata, "creationTimestamp")
+		if labels, ok := metadata["labels"].(map[string]interface{}); ok {
+			delete(labels, appsv1.DefaultDeploymentUniqueLabelKey)
+		}
+	}
+	out, err := yaml.Marshal(obj)
+	if err != nil {
+		return ""
+	}
+	return string(out)
+}
+
+// This function gives the lines removed ("- ") and added ("+ ") from a to b, in order, using their longest common subsequence
+func lineDiff(a string, b string) []string {
+	var x, y []string
+	if a != "" {
+		x = strings.Split(strings.TrimSuffix(a, "\n"), "\n")
+	}
+	if b != "" {
+		y = strings.Split(strings.TrimSuffix(b, "\n"), "\n")
+	}
+	lcs := make([][]int, len(x)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+	var diff []string
+	i, j := 0, 0
+	for i < len(x) || j < len(y) {
+		switch {
+		case i < len(x) && j < len(y) && x[i] == y[j]:
+			i++
+			j++
+		case j < len(y) && (i == len(x) || lcs[i][j+1] >= lcs[i+1][j]):
+			diff = append(diff, "+ "+y[j])
+			j++
+		default:
+			diff = append(diff, "- "+x[i])
+			i++
+		}
+	}
+	return diff
+}
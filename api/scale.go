@@ -0,0 +1,152 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"k8-api/operations"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/dynamic"
+)
+
+// ScaleResult is what a workload looks like once the scale went through, or once it was done waiting for it
+type ScaleResult struct {
+	Kind              string
+	Name              string
+	PreviousReplicas  int32
+	Replicas          int32
+	CurrentReplicas   int32
+	ReadyReplicas     int32
+	AvailableReplicas int32
+	Ready             bool
+}
+
+// These are the kinds that can be scaled. The scale subresource is the same for all of them, so it is used through
+// the dynamic client
+var scaleKinds = map[string]schema.GroupVersionResource{
+	"Deployment":            {Group: "apps", Version: "v1", Resource: "deployments"},
+	"StatefulSet":           {Group: "apps", Version: "v1", Resource: "statefulsets"},
+	"ReplicaSet":            {Group: "apps", Version: "v1", Resource: "replicasets"},
+	"ReplicationController": {Version: "v1", Resource: "replicationcontrollers"},
+}
+
+// This function reads the replica counts of the workload into result. The status fields have the same names for
+// every scalable kind, only a Deployment also has to have replaced all its old pods.
+func scaleStatus(ctx context.Context, ri dynamic.ResourceInterface, kind string, name string, result *ScaleResult) error {
+	obj, err := ri.Get(ctx, name, metav1.GetOptions{})
+	if err != nil {
+		return err
+	}
+	status := func(field string) int32 {
+		n, _, _ := unstructured.NestedInt64(obj.Object, "status", field)
+		return int32(n)
+	}
+	observed, _, _ := unstructured.NestedInt64(obj.Object, "status", "observedGeneration")
+	result.CurrentReplicas, result.ReadyReplicas, result.AvailableReplicas = status("replicas"), status("readyReplicas"), status("availableReplicas")
+	result.Ready = observed >= obj.GetGeneration() && result.scaled()
+	if kind == "Deployment" {
+		result.Ready = result.Ready && status("updatedReplicas") == result.Replicas
+	}
+	return nil
+}
+
+// Scaling down is only done once the extra pods are gone, not just when enough are ready
+func (r *ScaleResult) scaled() bool {
+	return r.CurrentReplicas == r.Replicas && r.ReadyReplicas == r.Replicas && r.AvailableReplicas == r.Replicas
+}
+
+// This function scales a Deployment, StatefulSet, ReplicaSet or ReplicationController through its scale subresource.
+// currentReplicas, when set, is a precondition: nothing changes unless the workload has that many replicas now.
+// With wait it runs as an operation and only ends once the new replica count is ready, or after timeout.
+func ScaleWorkload(ctx context.Context, kind string, namespace string, name string, replicas string, currentReplicas string, wait bool, timeout string, log *logrus.Entry) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+	gvr, ok := scaleKinds[kind]
+	if !ok {
+		log.Error("Kind must be Deployment, StatefulSet, ReplicaSet or ReplicationController")
+		return "Kind must be Deployment, StatefulSet, ReplicaSet or ReplicationController"
+	}
+	ri := Dynamic.Resource(gvr).Namespace(namespace)
+	count, err := strconv.Atoi(replicas)
+	if err != nil || count < 0 {
+		log.Error("Invalid replicas: " + replicas)
+		return "Invalid replicas: " + replicas
+	}
+	limit := 5 * time.Minute
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			log.Error("Invalid timeout: " + timeout)
+			return "Invalid timeout: " + timeout
+		}
+		limit = d
+	}
+
+	scale, err := ri.Get(ctx, name, metav1.GetOptions{}, "scale")
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	previous, _, _ := unstructured.NestedInt64(scale.Object, "spec", "replicas")
+	if currentReplicas != "" {
+		current, err := strconv.Atoi(currentReplicas)
+		if err != nil {
+			log.Error("Invalid currentReplicas: " + currentReplicas)
+			return "Invalid currentReplicas: " + currentReplicas
+		}
+		if int64(current) != previous {
+			log.Error(kind + ": " + name + " has " + strconv.Itoa(int(previous)) + " replicas, not " + currentReplicas + ". Not scaled")
+			return kind + ": " + name + " has " + strconv.Itoa(int(previous)) + " replicas, not " + currentReplicas + ". Not scaled"
+		}
+	}
+	result := ScaleResult{Kind: kind, Name: name, PreviousReplicas: int32(previous), Replicas: int32(count)}
+	// The scale carries the resourceVersion it was read at, so a change in between makes the update fail
+	if err := unstructured.SetNestedField(scale.Object, int64(count), "spec", "replicas"); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if _, err := ri.Update(ctx, scale, metav1.UpdateOptions{}, "scale"); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info(kind + ": " + name + " Scaled to " + replicas + "!")
+
+	if wait {
+		ctx, cancel := context.WithTimeout(ctx, limit)
+		defer cancel()
+		for {
+			if err := scaleStatus(ctx, ri, kind, name, &result); err != nil {
+				log.Error(err.Error())
+				return err.Error()
+			}
+			if result.Ready {
+				break
+			}
+			operations.Progress(ctx, strconv.Itoa(int(result.ReadyReplicas))+" of "+replicas+" replicas ready, "+strconv.Itoa(int(result.CurrentReplicas))+" running")
+			select {
+			case <-ctx.Done():
+				log.Error(kind + ": " + name + " not ready after " + limit.String())
+				return kind + ": " + name + " not ready after " + limit.String()
+			case <-time.After(2 * time.Second):
+			}
+		}
+	} else if err := scaleStatus(ctx, ri, kind, name, &result); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+
+	result_json, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(result_json)
+}
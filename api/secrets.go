@@ -0,0 +1,175 @@
+package api
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The secret types kubectl create secret knows, by the name it gives them
+var secretTypes = map[string]v1.SecretType{
+	"generic":         v1.SecretTypeOpaque,
+	"docker-registry": v1.SecretTypeDockerConfigJson,
+	"tls":             v1.SecretTypeTLS,
+	"basic-auth":      v1.SecretTypeBasicAuth,
+}
+
+// This function builds the .dockerconfigjson key of a docker-registry secret, the way kubectl create secret docker-registry does
+func DockerConfigJSON(server string, username string, password string, email string) ([]byte, error) {
+	if server == "" || username == "" || password == "" {
+		return nil, errors.New("dockerServer, dockerUsername and dockerPassword are all needed")
+	}
+	entry := map[string]string{
+		"username": username,
+		"password": password,
+		"auth":     base64.StdEncoding.EncodeToString([]byte(username + ":" + password)),
+	}
+	if email != "" {
+		entry["email"] = email
+	}
+	return json.Marshal(map[string]interface{}{"auths": map[string]interface{}{server: entry}})
+}
+
+// This function creates a Secret from the keys of the request. secretType is generic (the default), docker-registry,
+// tls or basic-auth (or the full type, e.g. kubernetes.io/tls); the keys each type needs are checked before it is created.
+func CreateSecret(namespace string, name string, secretType string, request KeyValueRequest, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	if err := request.check(false); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	t, ok := secretTypes[secretType]
+	if secretType == "" {
+		t = v1.SecretTypeOpaque
+	} else if !ok {
+		t = v1.SecretType(secretType)
+	}
+	secret := &v1.Secret{ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace}, Type: t, Data: request.Data}
+	if err := validateSecret(secret); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	secret, err := Kconfig.CoreV1().Secrets(namespace).Create(context.Background(), secret, metav1.CreateOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Secret: " + name + " Created!")
+	return secretResult(secret, log)
+}
+
+// This function sets and removes keys of a Secret, the other keys are left as they are. The result has to be valid
+// for the type of the Secret, which can not change. With a resourceVersion the update only goes through if nobody
+// changed the Secret since that version.
+func UpdateSecret(namespace string, name string, request KeyValueRequest, log *logrus.Entry) string {
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	if err := request.check(true); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	client := Kconfig.CoreV1().Secrets(namespace)
+	secret, err := client.Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	for _, key := range request.Remove {
+		if _, ok := secret.Data[key]; !ok {
+			log.Error("Secret: " + name + " has no key " + key)
+			return "Secret: " + name + " has no key " + key
+		}
+		delete(secret.Data, key)
+	}
+	for key, value := range request.Data {
+		if secret.Data == nil {
+			secret.Data = map[string][]byte{}
+		}
+		secret.Data[key] = value
+	}
+	if err := validateSecret(secret); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if request.ResourceVersion != "" {
+		secret.ResourceVersion = request.ResourceVersion
+	}
+	secret, err = client.Update(context.Background(), secret, metav1.UpdateOptions{})
+	if apierrors.IsConflict(err) {
+		log.Error("Secret: " + name + " was changed since it was read, get it again and retry. Error: " + err.Error())
+		return "Secret: " + name + " was changed since it was read, get it again and retry. Error: " + err.Error()
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Secret: " + name + " Updated!")
+	return secretResult(secret, log)
+}
+
+// This function checks the keys a typed Secret needs, so a broken one is refused here rather than by whatever reads it
+func validateSecret(secret *v1.Secret) error {
+	switch secret.Type {
+	case v1.SecretTypeDockerConfigJson:
+		config, ok := secret.Data[v1.DockerConfigJsonKey]
+		if !ok {
+			return errors.New("A docker-registry secret needs the " + v1.DockerConfigJsonKey + " key, or dockerServer, dockerUsername and dockerPassword")
+		}
+		var parsed struct {
+			Auths map[string]json.RawMessage `json:"auths"`
+		}
+		if err := json.Unmarshal(config, &parsed); err != nil {
+			return errors.New(v1.DockerConfigJsonKey + " is not valid JSON. Error: " + err.Error())
+		}
+		if len(parsed.Auths) == 0 {
+			return errors.New(v1.DockerConfigJsonKey + " has no registry under auths")
+		}
+	case v1.SecretTypeTLS:
+		cert, hasCert := secret.Data[v1.TLSCertKey]
+		key, hasKey := secret.Data[v1.TLSPrivateKeyKey]
+		if !hasCert || !hasKey {
+			return errors.New("A tls secret needs both the " + v1.TLSCertKey + " and " + v1.TLSPrivateKeyKey + " keys")
+		}
+		if _, err := tls.X509KeyPair(cert, key); err != nil {
+			return errors.New("Invalid certificate and key. Error: " + err.Error())
+		}
+	case v1.SecretTypeBasicAuth:
+		_, hasUsername := secret.Data[v1.BasicAuthUsernameKey]
+		_, hasPassword := secret.Data[v1.BasicAuthPasswordKey]
+		if !hasUsername && !hasPassword {
+			return errors.New("A basic-auth secret needs the " + v1.BasicAuthUsernameKey + " or " + v1.BasicAuthPasswordKey + " key")
+		}
+	}
+	return nil
+}
+
+func secretResult(secret *v1.Secret, log *logrus.Entry) string {
+	result := KeyValueResult{
+		Kind:            "Secret",
+		Name:            secret.Name,
+		Type:            string(secret.Type),
+		ResourceVersion: secret.ResourceVersion,
+		Keys:            sortedKeys(secret.Data),
+	}
+	result_json, err := json.Marshal(result)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(result_json)
+}
@@ -0,0 +1,187 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// This function sets container images in a Deployment, StatefulSet, DaemonSet or CronJob, from a JSON body
+// mapping container names to images ("*" sets every container and init container, as kubectl set image does).
+// The change-cause annotation records it, so it shows in the rollout history. With wait it runs as an operation
+// that ends once the rollout is done; CronJobs have no rollout to wait for. A Job's pod template can not change.
+func SetImage(ctx context.Context, kind string, namespace string, name string, body []byte, changeCause string, wait bool, timeout string, log *logrus.Entry) string {
+	if namespace == "" {
+		namespace = "default"
+	}
+	if kind == "Job" {
+		log.Error("The pod template of a Job can not change, set the image on its CronJob or create a new Job")
+		return "The pod template of a Job can not change, set the image on its CronJob or create a new Job"
+	}
+	var images map[string]string
+	if err := json.Unmarshal(body, &images); err != nil || len(images) == 0 {
+		log.Error("Body must map container names to images")
+		return "Body must map container names to images"
+	}
+	limit := 10 * time.Minute
+	if timeout != "" {
+		d, err := time.ParseDuration(timeout)
+		if err != nil || d <= 0 {
+			log.Error("Invalid timeout: " + timeout)
+			return "Invalid timeout: " + timeout
+		}
+		limit = d
+	}
+
+	spec, templatePath, err := workloadPodSpec(ctx, kind, namespace, name)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	containers, initContainers, problem := imageUpdates(spec, images)
+	if problem != "" {
+		log.Error(problem)
+		return problem
+	}
+	if changeCause == "" {
+		var pairs []string
+		for container, image := range images {
+			pairs = append(pairs, container+"="+image)
+		}
+		sort.Strings(pairs)
+		changeCause = "set image " + kind + "/" + name + " " + strings.Join(pairs, " ")
+	}
+
+	// A strategic merge patch matches the containers by name, so the others are left alone
+	podSpec := map[string]interface{}{}
+	if len(containers) > 0 {
+		podSpec["containers"] = containers
+	}
+	if len(initContainers) > 0 {
+		podSpec["initContainers"] = initContainers
+	}
+	var patch interface{} = map[string]interface{}{"template": map[string]interface{}{"spec": podSpec}}
+	for i := len(templatePath) - 1; i >= 0; i-- {
+		patch = map[string]interface{}{templatePath[i]: patch}
+	}
+	patch = map[string]interface{}{
+		"metadata": map[string]interface{}{"annotations": map[string]string{changeCauseAnnotation: changeCause}},
+		"spec":     patch,
+	}
+	patchBytes, err := json.Marshal(patch)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	ri, _, err := resourceInterface(workloadGroup(kind), "v1", strings.ToLower(kind)+"s", namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if _, err := ri.Patch(ctx, name, types.StrategicMergePatchType, patchBytes, metav1.PatchOptions{}); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info(kind + ": " + name + " Image updated! " + changeCause)
+
+	if !wait || kind == "CronJob" {
+		return kind + ": " + name + " Image updated!"
+	}
+	ctx, cancel := context.WithTimeout(ctx, limit)
+	defer cancel()
+	status, err := waitForRollout(ctx, kind, namespace, name)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return kind + ": " + name + " Image updated! " + status.Message
+}
+
+func workloadGroup(kind string) string {
+	if kind == "CronJob" {
+		return "batch"
+	}
+	return "apps"
+}
+
+// This function gets the pod spec of the workload, and the path under spec that leads to its template
+func workloadPodSpec(ctx context.Context, kind string, namespace string, name string) (*v1.PodSpec, []string, error) {
+	clientset := Kconfig
+	switch kind {
+	case "Deployment":
+		d, err := clientset.AppsV1().Deployments(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &d.Spec.Template.Spec, nil, nil
+	case "StatefulSet":
+		s, err := clientset.AppsV1().StatefulSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &s.Spec.Template.Spec, nil, nil
+	case "DaemonSet":
+		ds, err := clientset.AppsV1().DaemonSets(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &ds.Spec.Template.Spec, nil, nil
+	case "CronJob":
+		cj, err := clientset.BatchV1().CronJobs(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, nil, err
+		}
+		return &cj.Spec.JobTemplate.Spec.Template.Spec, []string{"jobTemplate", "spec"}, nil
+	}
+	return nil, nil, errors.New("Kind must be Deployment, StatefulSet, DaemonSet or CronJob")
+}
+
+// This function checks every container name against the pod spec and builds the patch entries for them
+func imageUpdates(spec *v1.PodSpec, images map[string]string) ([]map[string]string, []map[string]string, string) {
+	var containers, initContainers []map[string]string
+	all, wildcard := images["*"]
+	found := map[string]bool{}
+	for _, c := range spec.Containers {
+		image, ok := images[c.Name]
+		if !ok && wildcard {
+			image, ok = all, true
+		}
+		if ok {
+			containers = append(containers, map[string]string{"name": c.Name, "image": image})
+			found[c.Name] = true
+		}
+	}
+	for _, c := range spec.InitContainers {
+		image, ok := images[c.Name]
+		if !ok && wildcard {
+			image, ok = all, true
+		}
+		if ok {
+			initContainers = append(initContainers, map[string]string{"name": c.Name, "image": image})
+			found[c.Name] = true
+		}
+	}
+	var unknown []string
+	for container, image := range images {
+		if image == "" {
+			return nil, nil, "Image for container " + container + " is empty"
+		}
+		if container != "*" && !found[container] {
+			unknown = append(unknown, container)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, nil, "Unknown containers: " + strings.Join(unknown, ", ")
+	}
+	return containers, initContainers, ""
+}
@@ -0,0 +1,244 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	appsv1 "k8s.io/api/apps/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// These are all the Structs that are used for StatefulSets
+type Statefulset struct {
+	Name                 string
+	Replicas             int32
+	ReadyReplicas        int32
+	CurrentReplicas      int32
+	UpdatedReplicas      int32
+	CurrentRevision      string
+	UpdateRevision       string
+	ServiceName          string
+	VolumeClaimTemplates []VolumeClaimTemplate
+	Pods                 []StatefulsetPod
+	CreatedAt            string
+	UniqueID             string
+	Labels               map[string]string
+}
+
+type VolumeClaimTemplate struct {
+	Name         string
+	StorageClass string
+	Storage      string
+	AccessModes  []string
+}
+
+type StatefulsetPod struct {
+	Ordinal  int
+	Name     string
+	Status   string
+	Ready    bool
+	Revision string
+	NodeName string
+	IP       string
+}
+
+// This function is used to get the list of all the StatefulSets in the namespace
+func StatefulSets(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	statefulsets, err := clientset.AppsV1().StatefulSets(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find StatefulSets. Error: " + err.Error())
+		return err.Error()
+	}
+	var statefulsetInfo []Statefulset
+	for i := 0; i < len(statefulsets.Items); i++ {
+		info, err := statefulsetDetails(&statefulsets.Items[i])
+		if err != nil {
+			log.Error(err.Error())
+			return err.Error()
+		}
+		statefulsetInfo = append(statefulsetInfo, info)
+	}
+	statefulset_json, err := json.Marshal(statefulsetInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(statefulset_json)
+}
+
+// This function is used to get a single StatefulSet with its pods
+func StatefulSet(namespace string, statefulset string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if namespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		namespace = "default"
+	}
+	sts, err := clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), statefulset, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	info, err := statefulsetDetails(sts)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	statefulset_json, err := json.Marshal(info)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(statefulset_json)
+}
+
+func statefulsetDetails(sts *appsv1.StatefulSet) (Statefulset, error) {
+	info := Statefulset{
+		Name:            sts.Name,
+		ReadyReplicas:   sts.Status.ReadyReplicas,
+		CurrentReplicas: sts.Status.CurrentReplicas,
+		UpdatedReplicas: sts.Status.UpdatedReplicas,
+		CurrentRevision: sts.Status.CurrentRevision,
+		UpdateRevision:  sts.Status.UpdateRevision,
+		ServiceName:     sts.Spec.ServiceName,
+		CreatedAt:       sts.CreationTimestamp.String(),
+		UniqueID:        string(sts.UID),
+		Labels:          sts.Labels,
+	}
+	// A nil replicas means the default of one
+	info.Replicas = 1
+	if sts.Spec.Replicas != nil {
+		info.Replicas = *sts.Spec.Replicas
+	}
+
+	for _, pvc := range sts.Spec.VolumeClaimTemplates {
+		template := VolumeClaimTemplate{Name: pvc.Name}
+		if pvc.Spec.StorageClassName != nil {
+			template.StorageClass = *pvc.Spec.StorageClassName
+		}
+		if storage, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]; ok {
+			template.Storage = storage.String()
+		}
+		for _, mode := range pvc.Spec.AccessModes {
+			template.AccessModes = append(template.AccessModes, string(mode))
+		}
+		info.VolumeClaimTemplates = append(info.VolumeClaimTemplates, template)
+	}
+
+	pods, err := statefulsetPods(sts)
+	if err != nil {
+		return info, err
+	}
+	info.Pods = pods
+	return info, nil
+}
+
+// This function lists the pods owned by the StatefulSet, ordered by their ordinal
+func statefulsetPods(sts *appsv1.StatefulSet) ([]StatefulsetPod, error) {
+	clientset := Kconfig
+	selector, err := metav1.LabelSelectorAsSelector(sts.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	pods, err := clientset.CoreV1().Pods(sts.Namespace).List(context.Background(), metav1.ListOptions{LabelSelector: selector.String()})
+	if err != nil {
+		return nil, err
+	}
+	var podInfo []StatefulsetPod
+	for _, pod := range pods.Items {
+		owner := metav1.GetControllerOf(&pod)
+		if owner == nil || owner.UID != sts.UID {
+			continue
+		}
+		ordinal, ok := statefulsetOrdinal(sts.Name, pod.Name)
+		if !ok {
+			continue
+		}
+		podInfo = append(podInfo, StatefulsetPod{
+			Ordinal:  ordinal,
+			Name:     pod.Name,
+			Status:   string(pod.Status.Phase),
+			Ready:    podReady(&pod),
+			Revision: pod.Labels[appsv1.StatefulSetRevisionLabel],
+			NodeName: pod.Spec.NodeName,
+			IP:       pod.Status.PodIP,
+		})
+	}
+	sort.Slice(podInfo, func(i, j int) bool {
+		return podInfo[i].Ordinal < podInfo[j].Ordinal
+	})
+	return podInfo, nil
+}
+
+// StatefulSet pods and their claims are named <prefix>-<ordinal>
+func statefulsetOrdinal(prefix string, name string) (int, bool) {
+	if !strings.HasPrefix(name, prefix+"-") {
+		return 0, false
+	}
+	ordinal, err := strconv.Atoi(strings.TrimPrefix(name, prefix+"-"))
+	if err != nil || ordinal < 0 {
+		return 0, false
+	}
+	return ordinal, true
+}
+
+func podReady(pod *v1.Pod) bool {
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == v1.PodReady {
+			return condition.Status == v1.ConditionTrue
+		}
+	}
+	return false
+}
+
+// This function Deletes the StatefulSet. Unless retainPVCs is set, the claims made from its volumeClaimTemplates go too.
+func DeleteStatefulSet(namespace string, statefulset string, retainPVCs bool, log *logrus.Entry) string {
+	clientset := Kconfig
+	sts, err := clientset.AppsV1().StatefulSets(namespace).Get(context.Background(), statefulset, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if err := deleteStatefulSet(context.Background(), sts, retainPVCs, log); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("StatefulSet: " + statefulset + " Deleted!")
+	return "StatefulSet: " + statefulset + " Deleted!"
+}
+
+func deleteStatefulSet(ctx context.Context, sts *appsv1.StatefulSet, retainPVCs bool, log *logrus.Entry) error {
+	clientset := Kconfig
+	err := clientset.AppsV1().StatefulSets(sts.Namespace).Delete(ctx, sts.Name, metav1.DeleteOptions{})
+	if err != nil || retainPVCs || len(sts.Spec.VolumeClaimTemplates) == 0 {
+		return err
+	}
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(sts.Namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return err
+	}
+	for _, pvc := range pvcs.Items {
+		for _, template := range sts.Spec.VolumeClaimTemplates {
+			if _, ok := statefulsetOrdinal(template.Name+"-"+sts.Name, pvc.Name); !ok {
+				continue
+			}
+			if err := clientset.CoreV1().PersistentVolumeClaims(sts.Namespace).Delete(ctx, pvc.Name, metav1.DeleteOptions{}); err != nil {
+				return err
+			}
+			log.Info("PersistentVolumeClaim: " + pvc.Name + " Deleted!")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,291 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/sirupsen/logrus"
+
+	v1 "k8s.io/api/core/v1"
+	storagev1 "k8s.io/api/storage/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// These are all the Structs that are used for storage
+type Persistentvolumeclaim struct {
+	Name         string
+	Status       string
+	Volume       string
+	StorageClass string
+	Requested    string
+	Capacity     string
+	AccessModes  []string
+	VolumeMode   string
+	MountedBy    []string
+	CreatedAt    string
+	UniqueID     string
+	Labels       map[string]string
+}
+
+type Persistentvolume struct {
+	Name          string
+	Status        string
+	Claim         string
+	StorageClass  string
+	Capacity      string
+	AccessModes   []string
+	ReclaimPolicy string
+	VolumeMode    string
+	Reason        string
+	CreatedAt     string
+	UniqueID      string
+	Labels        map[string]string
+}
+
+type Storageclass struct {
+	Name                 string
+	Provisioner          string
+	ReclaimPolicy        string
+	VolumeBindingMode    string
+	AllowVolumeExpansion bool
+	Default              bool
+	Parameters           map[string]string
+	CreatedAt            string
+	UniqueID             string
+}
+
+// This function is used to get the list of all the PersistentVolumeClaims in the namespace with the pods mounting them
+func PersistentVolumeClaims(AgentNamespace string, log *logrus.Entry) string {
+	clientset := Kconfig
+	if AgentNamespace == "" {
+		log.Info("Namespace is empty")
+		log.Info("Namespace = default")
+		AgentNamespace = "default"
+	}
+	pvcs, err := clientset.CoreV1().PersistentVolumeClaims(AgentNamespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find PersistentVolumeClaims. Error: " + err.Error())
+		return err.Error()
+	}
+	mounts, err := podsByClaim(AgentNamespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var pvcInfo []Persistentvolumeclaim
+	for i := 0; i < len(pvcs.Items); i++ {
+		pvcInfo = append(pvcInfo, pvcDetails(&pvcs.Items[i], mounts))
+	}
+	pvc_json, err := json.Marshal(pvcInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(pvc_json)
+}
+
+// This function is used to get a single PersistentVolumeClaim with the pods mounting it
+func PersistentVolumeClaim(namespace string, pvc string, log *logrus.Entry) string {
+	clientset := Kconfig
+	claim, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(context.Background(), pvc, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	mounts, err := podsByClaim(namespace)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	pvc_json, err := json.Marshal(pvcDetails(claim, mounts))
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(pvc_json)
+}
+
+func pvcDetails(pvc *v1.PersistentVolumeClaim, mounts map[string][]string) Persistentvolumeclaim {
+	info := Persistentvolumeclaim{
+		Name:      pvc.Name,
+		Status:    string(pvc.Status.Phase),
+		Volume:    pvc.Spec.VolumeName,
+		MountedBy: mounts[pvc.Name],
+		CreatedAt: pvc.CreationTimestamp.String(),
+		UniqueID:  string(pvc.UID),
+		Labels:    pvc.Labels,
+	}
+	if pvc.Spec.StorageClassName != nil {
+		info.StorageClass = *pvc.Spec.StorageClassName
+	}
+	if requested, ok := pvc.Spec.Resources.Requests[v1.ResourceStorage]; ok {
+		info.Requested = requested.String()
+	}
+	if capacity, ok := pvc.Status.Capacity[v1.ResourceStorage]; ok {
+		info.Capacity = capacity.String()
+	}
+	for _, mode := range pvc.Spec.AccessModes {
+		info.AccessModes = append(info.AccessModes, string(mode))
+	}
+	if pvc.Spec.VolumeMode != nil {
+		info.VolumeMode = string(*pvc.Spec.VolumeMode)
+	}
+	return info
+}
+
+// This function maps every claim in the namespace to the pods that mount it
+func podsByClaim(namespace string) (map[string][]string, error) {
+	clientset := Kconfig
+	pods, err := clientset.CoreV1().Pods(namespace).List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	mounts := map[string][]string{}
+	for _, pod := range pods.Items {
+		for _, volume := range pod.Spec.Volumes {
+			if volume.PersistentVolumeClaim != nil {
+				mounts[volume.PersistentVolumeClaim.ClaimName] = append(mounts[volume.PersistentVolumeClaim.ClaimName], pod.Name)
+			}
+		}
+	}
+	return mounts, nil
+}
+
+// This function Deletes the PersistentVolumeClaim
+func DeletePersistentVolumeClaim(namespace string, pvc string, log *logrus.Entry) string {
+	clientset := Kconfig
+	err := clientset.CoreV1().PersistentVolumeClaims(namespace).Delete(context.Background(), pvc, metav1.DeleteOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("PersistentVolumeClaim: " + pvc + " Deleted!")
+	return "PersistentVolumeClaim: " + pvc + " Deleted!"
+}
+
+// This function grows a PersistentVolumeClaim, as long as its StorageClass allows volume expansion
+func ExpandPersistentVolumeClaim(namespace string, pvc string, size string, log *logrus.Entry) string {
+	clientset := Kconfig
+	quantity, err := resource.ParseQuantity(size)
+	if err != nil {
+		log.Error("Invalid size: " + size + ". Error: " + err.Error())
+		return "Invalid size: " + size
+	}
+	claim, err := clientset.CoreV1().PersistentVolumeClaims(namespace).Get(context.Background(), pvc, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if claim.Spec.StorageClassName == nil || *claim.Spec.StorageClassName == "" {
+		log.Error("PersistentVolumeClaim: " + pvc + " has no StorageClass, it cannot be expanded")
+		return "PersistentVolumeClaim: " + pvc + " has no StorageClass, it cannot be expanded"
+	}
+	class, err := clientset.StorageV1().StorageClasses().Get(context.Background(), *claim.Spec.StorageClassName, metav1.GetOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	if class.AllowVolumeExpansion == nil || !*class.AllowVolumeExpansion {
+		log.Error("StorageClass: " + class.Name + " does not allow volume expansion")
+		return "StorageClass: " + class.Name + " does not allow volume expansion"
+	}
+	current := claim.Spec.Resources.Requests[v1.ResourceStorage]
+	if quantity.Cmp(current) <= 0 {
+		log.Error("New size " + quantity.String() + " must be larger than the current " + current.String())
+		return "New size " + quantity.String() + " must be larger than the current " + current.String()
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"resources":{"requests":{"storage":%q}}}}`, quantity.String()))
+	_, err = clientset.CoreV1().PersistentVolumeClaims(namespace).Patch(context.Background(), pvc, types.MergePatchType, patch, metav1.PatchOptions{})
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("PersistentVolumeClaim: " + pvc + " Expanded to " + quantity.String() + "!")
+	return "PersistentVolumeClaim: " + pvc + " Expanded to " + quantity.String() + "!"
+}
+
+// This function is used to get the list of all the PersistentVolumes in the cluster
+func PersistentVolumes(log *logrus.Entry) string {
+	clientset := Kconfig
+	pvs, err := clientset.CoreV1().PersistentVolumes().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find PersistentVolumes. Error: " + err.Error())
+		return err.Error()
+	}
+	var pvInfo []Persistentvolume
+	for i := 0; i < len(pvs.Items); i++ {
+		pv := pvs.Items[i]
+		info := Persistentvolume{
+			Name:          pv.Name,
+			Status:        string(pv.Status.Phase),
+			StorageClass:  pv.Spec.StorageClassName,
+			ReclaimPolicy: string(pv.Spec.PersistentVolumeReclaimPolicy),
+			Reason:        pv.Status.Reason,
+			CreatedAt:     pv.CreationTimestamp.String(),
+			UniqueID:      string(pv.UID),
+			Labels:        pv.Labels,
+		}
+		if pv.Spec.ClaimRef != nil {
+			info.Claim = pv.Spec.ClaimRef.Namespace + "/" + pv.Spec.ClaimRef.Name
+		}
+		if capacity, ok := pv.Spec.Capacity[v1.ResourceStorage]; ok {
+			info.Capacity = capacity.String()
+		}
+		for _, mode := range pv.Spec.AccessModes {
+			info.AccessModes = append(info.AccessModes, string(mode))
+		}
+		if pv.Spec.VolumeMode != nil {
+			info.VolumeMode = string(*pv.Spec.VolumeMode)
+		}
+		pvInfo = append(pvInfo, info)
+	}
+	pv_json, err := json.Marshal(pvInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(pv_json)
+}
+
+// This function is used to get the list of all the StorageClasses in the cluster
+func StorageClasses(log *logrus.Entry) string {
+	clientset := Kconfig
+	classes, err := clientset.StorageV1().StorageClasses().List(context.Background(), metav1.ListOptions{})
+	if err != nil {
+		log.Error("Unable to find StorageClasses. Error: " + err.Error())
+		return err.Error()
+	}
+	var classInfo []Storageclass
+	for i := 0; i < len(classes.Items); i++ {
+		class := classes.Items[i]
+		info := Storageclass{
+			Name:                 class.Name,
+			Provisioner:          class.Provisioner,
+			AllowVolumeExpansion: class.AllowVolumeExpansion != nil && *class.AllowVolumeExpansion,
+			Default:              class.Annotations["storageclass.kubernetes.io/is-default-class"] == "true",
+			Parameters:           class.Parameters,
+			CreatedAt:            class.CreationTimestamp.String(),
+			UniqueID:             string(class.UID),
+		}
+		// Both of these have API defaults when they are left out
+		info.ReclaimPolicy = string(v1.PersistentVolumeReclaimDelete)
+		if class.ReclaimPolicy != nil {
+			info.ReclaimPolicy = string(*class.ReclaimPolicy)
+		}
+		info.VolumeBindingMode = string(storagev1.VolumeBindingImmediate)
+		if class.VolumeBindingMode != nil {
+			info.VolumeBindingMode = string(*class.VolumeBindingMode)
+		}
+		classInfo = append(classInfo, info)
+	}
+	class_json, err := json.Marshal(classInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(class_json)
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"io"
 	"io/ioutil"
+	"k8-api/operations"
 	"os"
 
 	"github.com/sirupsen/logrus"
@@ -20,14 +21,17 @@ import (
 	"k8s.io/client-go/tools/clientcmd"
 )
 
-func Main(filename string, log *logrus.Entry) string {
+// Main applies every object in the file. It runs as an operation, so each object is reported as a step
+// and it stops between objects once ctx is cancelled.
+func Main(ctx context.Context, filename string, log *logrus.Entry) string {
 
 	b, err := ioutil.ReadFile(filename)
 	if err != nil {
 		log.Error(err.Error())
 		return (err.Error())
 	}
-	log.Info("%q \n", string(b))
+	// Only the file name and the objects are logged, the contents can hold Secret data
+	log.Info("Applying " + filename)
 
 	kubeconfig := os.Getenv("KUBECONFIG")
 
@@ -47,9 +51,13 @@ func Main(filename string, log *logrus.Entry) string {
 
 	decoder := yamlutil.NewYAMLOrJSONDecoder(bytes.NewReader(b), 100)
 	for {
+		if ctx.Err() != nil {
+			log.Warn("Applying " + filename + " cancelled")
+			return "Applying " + filename + " cancelled"
+		}
+
 		var rawObj runtime.RawExtension
 		if err = decoder.Decode(&rawObj); err != nil {
-			log.Error(err.Error())
 			break
 		}
 
@@ -90,7 +98,10 @@ func Main(filename string, log *logrus.Entry) string {
 			dri = dd.Resource(mapping.Resource)
 		}
 
-		if _, err := dri.Create(context.Background(), unstructuredObj, metav1.CreateOptions{}); err != nil {
+		log.Info("Creating " + gvk.Kind + "/" + unstructuredObj.GetName())
+		_, err = dri.Create(ctx, unstructuredObj, metav1.CreateOptions{})
+		operations.Step(ctx, gvk.Kind+"/"+unstructuredObj.GetName(), "Created", err)
+		if err != nil {
 			log.Error(err.Error())
 			return (err.Error())
 		}
@@ -13,6 +13,8 @@ import (
 
 	"gopkg.in/yaml.v2"
 
+	"k8-api/operations"
+
 	"github.com/gofrs/flock"
 	"github.com/pkg/errors"
 	"github.com/sirupsen/logrus"
@@ -143,8 +145,8 @@ func RepoUpdate(log *logrus.Entry) string {
 	return "Update Complete. ⎈ Happy Helming!⎈"
 }
 
-// InstallChart
-func InstallChart(name, repo, chart, namespace string, log *logrus.Entry) string {
+// InstallChart installs the chart as a release. It runs as an operation and gives up once ctx is cancelled.
+func InstallChart(ctx context.Context, name, repo, chart, namespace string, log *logrus.Entry) string {
 	os.Setenv("HELM_NAMESPACE", namespace)
 	actionConfig := new(action.Configuration)
 	if err := actionConfig.Init(settings.RESTClientGetter(), settings.Namespace(), os.Getenv("HELM_DRIVER"), debug); err != nil {
@@ -158,6 +160,7 @@ func InstallChart(name, repo, chart, namespace string, log *logrus.Entry) string
 	}
 	//name, chart, err := client.NameAndChart(args)
 	client.ReleaseName = name
+	operations.Progress(ctx, "Locating chart "+repo+"/"+chart)
 	cp, err := client.ChartPathOptions.LocateChart(fmt.Sprintf("%s/%s", repo, chart), settings)
 	if err != nil {
 		log.Error(err.Error())
@@ -217,7 +220,8 @@ func InstallChart(name, repo, chart, namespace string, log *logrus.Entry) string
 	}
 
 	client.Namespace = settings.Namespace()
-	release, err := client.Run(chartRequested, vals)
+	operations.Progress(ctx, "Installing release "+name)
+	release, err := client.RunWithContext(ctx, chartRequested, vals)
 	if err != nil {
 		log.Error(err.Error())
 		return err.Error()
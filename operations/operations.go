@@ -0,0 +1,438 @@
+package operations
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"k8-api/store"
+
+	"github.com/distribution/distribution/v3/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// These are the states an operation moves through
+const (
+	Queued    = "Queued"
+	Running   = "Running"
+	Succeeded = "Succeeded"
+	Failed    = "Failed"
+	Cancelled = "Cancelled"
+)
+
+// Func is the work an operation runs. It should stop as soon as ctx is cancelled.
+// Anything logged at Error level or above through log marks the operation as Failed.
+type Func func(ctx context.Context, log *logrus.Entry) string
+
+// These are all the Structs that are returned by the operation routes
+type Operation struct {
+	ID         string
+	Kind       string
+	Status     string
+	Result     string
+	Messages   []string
+	Steps      []StepResult
+	Logs       []string
+	CreatedAt  string
+	StartedAt  string
+	FinishedAt string
+
+	mu       sync.Mutex
+	ctx      context.Context
+	cancel   context.CancelFunc
+	fn       Func
+	log      *logrus.Entry
+	failed   bool
+	created  time.Time
+	finished time.Time
+}
+
+type StepResult struct {
+	Name   string
+	Status string
+	Result string
+	Time   string
+}
+
+type contextKey struct{}
+
+var (
+	mu         sync.Mutex
+	operations = map[string]*Operation{}
+	queue      chan *Operation
+	retention  = time.Hour
+	// Audit records are kept in the store, this long
+	auditRetention = 7 * 24 * time.Hour
+)
+
+// Start launches the worker pool and the janitor that forgets finished operations and old audit records.
+// The pool size and the retention periods come from the operationWorkers, operationRetention and auditRetention
+// settings, or else from OPERATION_WORKERS, OPERATION_RETENTION and AUDIT_RETENTION. The pool size is only read
+// here, the janitor reads the retention periods again on every run so a changed setting applies without a restart.
+func Start(log *logrus.Entry) {
+	workers := 4
+	if v := store.GetSetting("operationWorkers", os.Getenv("OPERATION_WORKERS")); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 {
+			log.Error("Invalid OPERATION_WORKERS: " + v + ", using 4")
+		} else {
+			workers = n
+		}
+	}
+	var err error
+	if retention, err = durationSetting("operationRetention", "OPERATION_RETENTION", time.Hour); err != nil {
+		log.Error(err.Error() + ", using 1h")
+	}
+	if auditRetention, err = durationSetting("auditRetention", "AUDIT_RETENTION", 7*24*time.Hour); err != nil {
+		log.Error(err.Error() + ", using 168h")
+	}
+
+	interrupted(log)
+
+	queue = make(chan *Operation, 100)
+	for i := 0; i < workers; i++ {
+		go worker()
+	}
+	go janitor(log)
+	log.Info("Operation workers started: " + strconv.Itoa(workers) + ", retention: " + retention.String() + ", audit retention: " + auditRetention.String())
+}
+
+// Submit queues fn to run in the worker pool and returns the operation tracking it.
+func Submit(kind string, log *logrus.Entry, fn Func) *Operation {
+	ctx, cancel := context.WithCancel(context.Background())
+	op := &Operation{
+		ID:        "op-" + uuid.Generate().String()[:8],
+		Kind:      kind,
+		Status:    Queued,
+		CreatedAt: time.Now().Format(time.RFC3339),
+		created:   time.Now(),
+		cancel:    cancel,
+		fn:        fn,
+	}
+	op.ctx = context.WithValue(ctx, contextKey{}, op)
+
+	// Every operation gets its own logger so that its log lines can be kept with it
+	logger := logrus.New()
+	logger.Out = log.Logger.Out
+	logger.Formatter = log.Logger.Formatter
+	logger.ReportCaller = log.Logger.ReportCaller
+	logger.Level = log.Logger.Level
+	logger.AddHook(op)
+	op.log = logger.WithFields(log.Data).WithField("operation", op.ID)
+
+	mu.Lock()
+	operations[op.ID] = op
+	mu.Unlock()
+	save(op)
+
+	select {
+	case queue <- op:
+		op.log.Info(kind + " operation queued")
+	default:
+		op.finish(Failed, "Operation queue is full, try again later")
+		op.log.Warn(kind + " operation rejected, queue is full")
+	}
+	return op
+}
+
+// Progress records a progress message on the operation running with ctx, if any.
+func Progress(ctx context.Context, message string) {
+	op, ok := ctx.Value(contextKey{}).(*Operation)
+	if !ok {
+		return
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.Messages = append(op.Messages, message)
+}
+
+// Step records the result of one step of the operation running with ctx, if any.
+func Step(ctx context.Context, name string, result string, err error) {
+	op, ok := ctx.Value(contextKey{}).(*Operation)
+	if !ok {
+		return
+	}
+	step := StepResult{Name: name, Status: Succeeded, Result: result, Time: time.Now().Format(time.RFC3339)}
+	if err != nil {
+		step.Status = Failed
+		step.Result = err.Error()
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.Steps = append(op.Steps, step)
+	if err != nil {
+		op.failed = true
+	}
+}
+
+// Levels and Fire make an Operation a logrus hook, this is how it collects its logs.
+func (op *Operation) Levels() []logrus.Level {
+	return logrus.AllLevels
+}
+
+func (op *Operation) Fire(entry *logrus.Entry) error {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.Logs = append(op.Logs, fmt.Sprintf("%s [%s] %s", entry.Time.Format(time.RFC3339), entry.Level.String(), entry.Message))
+	if entry.Level <= logrus.ErrorLevel {
+		op.failed = true
+	}
+	return nil
+}
+
+func (op *Operation) finish(status string, result string) {
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op.Status = status
+	op.Result = result
+	op.FinishedAt = time.Now().Format(time.RFC3339)
+	op.finished = time.Now()
+	op.cancel()
+	save(op)
+}
+
+func (op *Operation) run() {
+	op.mu.Lock()
+	if op.Status != Queued {
+		// Cancelled before a worker picked it up
+		op.mu.Unlock()
+		return
+	}
+	op.Status = Running
+	op.StartedAt = time.Now().Format(time.RFC3339)
+	op.mu.Unlock()
+
+	op.log.Info(op.Kind + " operation started")
+	result := op.fn(op.ctx, op.log)
+
+	op.mu.Lock()
+	failed := op.failed
+	op.mu.Unlock()
+	switch {
+	case op.ctx.Err() != nil:
+		op.finish(Cancelled, result)
+	case failed:
+		op.finish(Failed, result)
+	default:
+		op.finish(Succeeded, result)
+	}
+	op.log.Info(op.Kind + " operation finished")
+}
+
+// This function reads a duration from a setting, or else from the env variable. An invalid value gives back the
+// fallback along with the error.
+func durationSetting(key string, env string, fallback time.Duration) (time.Duration, error) {
+	v := store.GetSetting(key, os.Getenv(env))
+	if v == "" {
+		return fallback, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d <= 0 {
+		return fallback, fmt.Errorf("Invalid %s: %s", env, v)
+	}
+	return d, nil
+}
+
+func worker() {
+	for op := range queue {
+		op.run()
+	}
+}
+
+// This function drops finished operations once they are older than the retention period, from memory and from
+// the store, and the audit records older than the audit retention period
+func janitor(log *logrus.Entry) {
+	// An invalid setting is only logged when it changes, not every minute
+	invalid := map[string]string{}
+	reread := func(key string, env string, fallback time.Duration) time.Duration {
+		d, err := durationSetting(key, env, fallback)
+		problem := ""
+		if err != nil {
+			problem = err.Error()
+		}
+		if problem != "" && invalid[key] != problem {
+			log.Error(problem + ", using " + fallback.String())
+		}
+		invalid[key] = problem
+		return d
+	}
+	for range time.Tick(time.Minute) {
+		retention = reread("operationRetention", "OPERATION_RETENTION", time.Hour)
+		auditRetention = reread("auditRetention", "AUDIT_RETENTION", 7*24*time.Hour)
+		store.TrimAudit(time.Now().Add(-auditRetention), log)
+		store.TrimOperations(time.Now().Add(-retention), log)
+		mu.Lock()
+		for id, op := range operations {
+			op.mu.Lock()
+			expired := !op.finished.IsZero() && time.Since(op.finished) > retention
+			op.mu.Unlock()
+			if expired {
+				delete(operations, id)
+			}
+		}
+		mu.Unlock()
+	}
+}
+
+// This function keeps a copy of the operation in the store, so its history survives restarts
+func save(op *Operation) {
+	if store.DB == nil {
+		return
+	}
+	if err := store.DB.Put(store.Operations, op.ID, op); err != nil {
+		logrus.Error("Unable to save operation " + op.ID + ". Error: " + err.Error())
+	}
+}
+
+// This function marks the operations that were still queued or running when kube-ez stopped
+func interrupted(log *logrus.Entry) {
+	if store.DB == nil {
+		return
+	}
+	values, err := store.DB.List(store.Operations)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	for _, value := range values {
+		op := &Operation{}
+		if err := json.Unmarshal(value, op); err != nil {
+			log.Error(err.Error())
+			continue
+		}
+		if op.Status != Queued && op.Status != Running {
+			continue
+		}
+		op.Status = Failed
+		op.Result = "Interrupted by a restart of kube-ez"
+		op.FinishedAt = time.Now().Format(time.RFC3339)
+		save(op)
+		log.Warn("Operation " + op.ID + " was interrupted by a restart")
+	}
+}
+
+func lookup(id string) (*Operation, bool) {
+	mu.Lock()
+	defer mu.Unlock()
+	op, ok := operations[id]
+	return op, ok
+}
+
+// This function returns the operation with the given id as JSON
+func Get(id string, log *logrus.Entry) string {
+	op, ok := lookup(id)
+	if !ok {
+		// Operations past their retention, or from before a restart, are still in the store
+		op = &Operation{}
+		if store.DB == nil || store.DB.Get(store.Operations, id, op) != nil {
+			log.Error("Operation " + id + " not found")
+			return "Operation: " + id + " not found"
+		}
+	}
+	op.mu.Lock()
+	defer op.mu.Unlock()
+	op_json, err := json.Marshal(op)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(op_json)
+}
+
+// This function returns the list of all the known operations, newest first
+func List(log *logrus.Entry) string {
+	mu.Lock()
+	var ops []*Operation
+	for _, op := range operations {
+		ops = append(ops, op)
+	}
+	mu.Unlock()
+
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].created.After(ops[j].created)
+	})
+
+	var operationsInfo []Operation
+	for _, op := range ops {
+		op.mu.Lock()
+		operationsInfo = append(operationsInfo, Operation{
+			ID:         op.ID,
+			Kind:       op.Kind,
+			Status:     op.Status,
+			Result:     op.Result,
+			CreatedAt:  op.CreatedAt,
+			StartedAt:  op.StartedAt,
+			FinishedAt: op.FinishedAt,
+		})
+		op.mu.Unlock()
+	}
+	operations_json, err := json.Marshal(operationsInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(operations_json)
+}
+
+// This function returns every operation kept in the store, newest first
+func History(log *logrus.Entry) string {
+	if store.DB == nil {
+		return "Store is not available"
+	}
+	values, err := store.DB.List(store.Operations)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	var ops []*Operation
+	for _, value := range values {
+		op := &Operation{}
+		if err := json.Unmarshal(value, op); err != nil {
+			log.Error(err.Error())
+			continue
+		}
+		// The history only lists them, the details are on /operations/<id>
+		op.Messages, op.Steps, op.Logs = nil, nil, nil
+		ops = append(ops, op)
+	}
+	sort.Slice(ops, func(i, j int) bool {
+		return ops[i].CreatedAt > ops[j].CreatedAt
+	})
+	history_json, err := json.Marshal(ops)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(history_json)
+}
+
+// This function cancels a queued or running operation
+func Cancel(id string, log *logrus.Entry) string {
+	op, ok := lookup(id)
+	if !ok {
+		log.Error("Operation " + id + " not found")
+		return "Operation: " + id + " not found"
+	}
+	op.mu.Lock()
+	status := op.Status
+	if status == Queued {
+		op.Status = Cancelled
+		op.Result = "Cancelled before it started"
+		op.FinishedAt = time.Now().Format(time.RFC3339)
+		op.finished = time.Now()
+		save(op)
+	}
+	op.mu.Unlock()
+	if status != Queued && status != Running {
+		return "Operation: " + id + " already " + status
+	}
+	// A running operation is marked Cancelled by its worker once the function notices
+	op.cancel()
+	log.Info("Operation: " + id + " Cancelled!")
+	return "Operation: " + id + " Cancelled!"
+}
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
+	"io/ioutil"
 	api "k8-api/api"
 	apply "k8-api/apply"
 	"k8-api/install"
+	"k8-api/operations"
+	"k8-api/store"
 	"net/http"
+	"path/filepath"
 	"runtime"
+	"strings"
 	"time"
 
 	"github.com/distribution/distribution/v3/uuid"
@@ -48,6 +54,144 @@ func retryMax(next echo.HandlerFunc) echo.HandlerFunc {
 	}
 }
 
+// The patch routes share these query parameters
+func patchRequest(c echo.Context) api.PatchRequest {
+	return api.PatchRequest{
+		Type:         c.QueryParam("type"),
+		FieldManager: c.QueryParam("fieldManager"),
+		DryRun:       c.QueryParam("dryRun") == "True" || c.QueryParam("dryRun") == "true",
+		Force:        c.QueryParam("force") == "True" || c.QueryParam("force") == "true",
+	}
+}
+
+// The ConfigMap and Secret routes take keys from a JSON or env-file body (format), or from a form: in a
+// urlencoded or multipart form a "data" field holds a JSON object and an "envFile" field an env-file, and in a
+// multipart form uploaded files are keyed by their field name, or by their file name when the field is "file",
+// while files under "envFile" are read as env-files. remove is a comma separated list of keys.
+func keyValueRequest(c echo.Context) (api.KeyValueRequest, error) {
+	request := api.KeyValueRequest{Data: map[string][]byte{}}
+	add := func(data map[string][]byte) error {
+		for key, value := range data {
+			if _, ok := request.Data[key]; ok {
+				return errors.New("Key " + key + " is given twice")
+			}
+			request.Data[key] = value
+		}
+		return nil
+	}
+
+	contentType := c.Request().Header.Get(echo.HeaderContentType)
+	switch {
+	case strings.HasPrefix(contentType, echo.MIMEMultipartForm):
+		form, err := c.MultipartForm()
+		if err != nil {
+			return request, err
+		}
+		for field, files := range form.File {
+			for _, file := range files {
+				f, err := file.Open()
+				if err != nil {
+					return request, err
+				}
+				content, err := ioutil.ReadAll(f)
+				f.Close()
+				if err != nil {
+					return request, err
+				}
+				data := map[string][]byte{}
+				switch field {
+				case "envFile":
+					data, err = api.KeyValues("env", content)
+					if err != nil {
+						return request, errors.New(file.Filename + ": " + err.Error())
+					}
+				case "file":
+					data[filepath.Base(file.Filename)] = content
+				default:
+					data[field] = content
+				}
+				if err := add(data); err != nil {
+					return request, err
+				}
+			}
+		}
+		if err := formKeyValues(c, add); err != nil {
+			return request, err
+		}
+	case strings.HasPrefix(contentType, echo.MIMEApplicationForm):
+		if _, err := c.FormParams(); err != nil {
+			return request, err
+		}
+		if err := formKeyValues(c, add); err != nil {
+			return request, err
+		}
+	default:
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			return request, err
+		}
+		if len(strings.TrimSpace(string(body))) > 0 {
+			data, err := api.KeyValues(c.QueryParam("format"), body)
+			if err != nil {
+				return request, err
+			}
+			request.Data = data
+		}
+	}
+
+	request.ResourceVersion = c.FormValue("resourceVersion")
+	for _, key := range strings.Split(c.FormValue("remove"), ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			request.Remove = append(request.Remove, key)
+		}
+	}
+	return request, nil
+}
+
+// The "data" and "envFile" fields of a form, read from the body only
+func formKeyValues(c echo.Context, add func(map[string][]byte) error) error {
+	if values := c.Request().PostFormValue("data"); values != "" {
+		data, err := api.KeyValues("json", []byte(values))
+		if err != nil {
+			return err
+		}
+		if err := add(data); err != nil {
+			return err
+		}
+	}
+	if env := c.Request().PostFormValue("envFile"); env != "" {
+		data, err := api.KeyValues("env", []byte(env))
+		if err != nil {
+			return err
+		}
+		if err := add(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// The registry credentials of a docker-registry secret are only read from the form in the body, the query string
+// ends up in the access log and the audit records
+func dockerConfigRequest(c echo.Context, request *api.KeyValueRequest) error {
+	query := c.Request().URL.Query()
+	for _, field := range []string{"dockerServer", "dockerUsername", "dockerPassword", "dockerEmail"} {
+		if _, ok := query[field]; ok {
+			return errors.New(field + " must be sent in the request body as a form field, not in the query string")
+		}
+	}
+	server := c.Request().PostFormValue("dockerServer")
+	if server == "" {
+		return nil
+	}
+	config, err := api.DockerConfigJSON(server, c.Request().PostFormValue("dockerUsername"), c.Request().PostFormValue("dockerPassword"), c.Request().PostFormValue("dockerEmail"))
+	if err != nil {
+		return err
+	}
+	request.Data[".dockerconfigjson"] = config
+	return nil
+}
+
 func main() {
 
 	e := echo.New()
@@ -82,10 +226,30 @@ func main() {
 		}
 	})
 
+	// Middleware to keep an audit record of every request that can change something
+	e.Use(func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			err := next(c)
+			method := c.Request().Method
+			if method != echo.GET && method != echo.HEAD && method != echo.OPTIONS {
+				// Only the path is kept, the query string can carry values that should not be
+				store.AddAudit(store.AuditRecord{
+					Time:      time.Now().Format(time.RFC3339),
+					RequestID: fmt.Sprint(c.Get("uuid")),
+					Method:    method,
+					URI:       c.Request().URL.Path,
+					RemoteIP:  c.RealIP(),
+					Status:    c.Response().Status,
+				}, log.WithFields(logrus.Fields{"uuid": c.Get("uuid")}))
+			}
+			return err
+		}
+	})
+
 	// Middleware to set the order of the log that is genererated
 	e.Use(middleware.LoggerWithConfig(middleware.LoggerConfig{
 		Format: `{"level":"INFO","time":"${time_rfc3339_nano}","id":"${id}","remote_ip":"${remote_ip}",` +
-			`"host":"${host}","method":"${method}","uri":"${uri}","user_agent":"${user_agent}",` +
+			`"host":"${host}","method":"${method}","path":"${path}","user_agent":"${user_agent}",` +
 			`"status":${status},"error":"${error}","latency":${latency},"latency_human":"${latency_human}"` +
 			`,"bytes_in":${bytes_in},"bytes_out":${bytes_out}}` + "\n",
 		CustomTimeFormat: "2006-01-02 15:04:05",
@@ -95,6 +259,10 @@ func main() {
 	e.Use(timeoutMiddleware, retryMax)
 	// Calling the Main fucntion that connects with the kubernetes cluster
 	api.Main()
+	// Opening the local store that keeps operations, audit records, clusters and settings across restarts
+	store.Main()
+	// Starting the worker pool that runs the long operations in the background
+	operations.Start(logrus.NewEntry(log))
 
 	//Middlewae to handle CORS
 	e.Use(middleware.CORSWithConfig(middleware.CORSConfig{
@@ -121,6 +289,12 @@ func main() {
 		return c.String(http.StatusOK, api.NameSpace(l))
 	})
 
+	e.GET("/nodes", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Nodes intitiated")
+		return c.String(http.StatusOK, api.Nodes(l))
+	})
+
 	e.GET("/deployments", func(c echo.Context) error {
 		namespace := c.QueryParam("namespace")
 		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
@@ -130,9 +304,10 @@ func main() {
 
 	e.GET("/configmaps", func(c echo.Context) error {
 		namespace := c.QueryParam("namespace")
+		valueLimit := c.QueryParam("valueLimit")
 		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
 		l.Info("Get Configmaps intitiated")
-		return c.String(http.StatusOK, api.Configmaps(namespace, l))
+		return c.String(http.StatusOK, api.Configmaps(namespace, valueLimit, l))
 	})
 
 	e.GET("/services", func(c echo.Context) error {
@@ -144,9 +319,20 @@ func main() {
 
 	e.GET("/events", func(c echo.Context) error {
 		namespace := c.QueryParam("namespace")
+		query := api.EventQuery{
+			Kind:   c.QueryParam("kind"),
+			Name:   c.QueryParam("name"),
+			UID:    c.QueryParam("uid"),
+			Type:   c.QueryParam("type"),
+			Reason: c.QueryParam("reason"),
+			Since:  c.QueryParam("since"),
+			Until:  c.QueryParam("until"),
+			Order:  c.QueryParam("order"),
+			API:    c.QueryParam("api"),
+		}
 		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
 		l.Info("Get Events intitiated")
-		return c.String(http.StatusOK, api.Events(namespace, l))
+		return c.String(http.StatusOK, api.Events(namespace, query, l))
 	})
 
 	e.GET("/secrets", func(c echo.Context) error {
@@ -170,6 +356,302 @@ func main() {
 		return c.String(http.StatusOK, api.DaemonSet(namespace, l))
 	})
 
+	e.GET("/rolloutHistory", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		deployment := c.QueryParam("deployment")
+		revision := c.QueryParam("revision")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Deployment rollout history intitiated")
+		return c.String(http.StatusOK, api.DeploymentHistory(context.Background(), namespace, deployment, revision, l))
+	})
+
+	e.GET("/rolloutStatus", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		deployment := c.QueryParam("deployment")
+		wait := c.QueryParam("wait") == "True" || c.QueryParam("wait") == "true"
+		timeout := c.QueryParam("timeout")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Deployment rollout status intitiated")
+		if !wait {
+			return c.String(http.StatusOK, api.DeploymentRolloutStatus(context.Background(), namespace, deployment, false, timeout, l))
+		}
+		op := operations.Submit("rolloutStatus", l, func(ctx context.Context, l *logrus.Entry) string {
+			return api.DeploymentRolloutStatus(ctx, namespace, deployment, true, timeout, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
+	})
+
+	e.GET("/daemonSetRolloutStatus", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		daemonSet := c.QueryParam("daemonSet")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("DaemonSet rollout status intitiated")
+		return c.String(http.StatusOK, api.DaemonSetRolloutStatus(namespace, daemonSet, l))
+	})
+
+	e.POST("/restartDaemonSet", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		daemonSet := c.FormValue("daemonSet")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Restart DaemonSet intitiated")
+		return c.String(http.StatusOK, api.RestartDaemonSet(namespace, daemonSet, l))
+	})
+
+	e.GET("/statefulsets", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get StatefulSets intitiated")
+		return c.String(http.StatusOK, api.StatefulSets(namespace, l))
+	})
+
+	e.GET("/statefulset", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		statefulSet := c.QueryParam("statefulSet")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get StatefulSet intitiated")
+		return c.String(http.StatusOK, api.StatefulSet(namespace, statefulSet, l))
+	})
+
+	e.GET("/jobs", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Jobs intitiated")
+		return c.String(http.StatusOK, api.Jobs(namespace, l))
+	})
+
+	e.GET("/jobLogs", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		job := c.QueryParam("job")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Job's Logs intitiated")
+		return c.String(http.StatusOK, api.JobLogs(namespace, job, l))
+	})
+
+	e.GET("/cronjobs", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get CronJobs intitiated")
+		return c.String(http.StatusOK, api.CronJobs(namespace, l))
+	})
+
+	e.GET("/cronJobHistory", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		cronJob := c.QueryParam("cronJob")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get CronJob History intitiated")
+		return c.String(http.StatusOK, api.CronJobHistory(namespace, cronJob, l))
+	})
+
+	e.GET("/ingresses", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Ingresses intitiated")
+		return c.String(http.StatusOK, api.Ingresses(namespace, l))
+	})
+
+	e.GET("/ingressClasses", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get IngressClasses intitiated")
+		return c.String(http.StatusOK, api.IngressClasses(l))
+	})
+
+	e.GET("/ingressLookup", func(c echo.Context) error {
+		host := c.QueryParam("host")
+		path := c.QueryParam("path")
+		ingressClass := c.QueryParam("ingressClass")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Ingress Lookup intitiated")
+		return c.String(http.StatusOK, api.IngressLookup(host, path, ingressClass, l))
+	})
+
+	e.GET("/pvcs", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get PersistentVolumeClaims intitiated")
+		return c.String(http.StatusOK, api.PersistentVolumeClaims(namespace, l))
+	})
+
+	e.GET("/pvc", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		pvc := c.QueryParam("pvc")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get PersistentVolumeClaim intitiated")
+		return c.String(http.StatusOK, api.PersistentVolumeClaim(namespace, pvc, l))
+	})
+
+	e.GET("/pvs", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get PersistentVolumes intitiated")
+		return c.String(http.StatusOK, api.PersistentVolumes(l))
+	})
+
+	e.GET("/storageClasses", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get StorageClasses intitiated")
+		return c.String(http.StatusOK, api.StorageClasses(l))
+	})
+
+	e.GET("/hpas", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get HorizontalPodAutoscalers intitiated")
+		return c.String(http.StatusOK, api.HPAs(namespace, l))
+	})
+
+	e.GET("/networkPolicies", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get NetworkPolicies intitiated")
+		return c.String(http.StatusOK, api.NetworkPolicies(namespace, l))
+	})
+
+	e.GET("/networkPolicyCheck", func(c echo.Context) error {
+		srcNamespace := c.QueryParam("srcNamespace")
+		srcPod := c.QueryParam("srcPod")
+		dstNamespace := c.QueryParam("dstNamespace")
+		dstPod := c.QueryParam("dstPod")
+		port := c.QueryParam("port")
+		protocol := c.QueryParam("protocol")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("NetworkPolicy check intitiated")
+		return c.String(http.StatusOK, api.NetworkPolicyCheck(srcNamespace, srcPod, dstNamespace, dstPod, port, protocol, l))
+	})
+
+	e.GET("/serviceAccounts", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get ServiceAccounts intitiated")
+		return c.String(http.StatusOK, api.ServiceAccounts(namespace, l))
+	})
+
+	e.GET("/roles", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Roles intitiated")
+		return c.String(http.StatusOK, api.Roles(namespace, l))
+	})
+
+	e.GET("/clusterRoles", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get ClusterRoles intitiated")
+		return c.String(http.StatusOK, api.ClusterRoles(l))
+	})
+
+	e.GET("/roleBindings", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get RoleBindings intitiated")
+		return c.String(http.StatusOK, api.RoleBindings(namespace, l))
+	})
+
+	e.GET("/clusterRoleBindings", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get ClusterRoleBindings intitiated")
+		return c.String(http.StatusOK, api.ClusterRoleBindings(l))
+	})
+
+	e.GET("/whoCan", func(c echo.Context) error {
+		verb := c.QueryParam("verb")
+		resource := c.QueryParam("resource")
+		group := c.QueryParam("group")
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Who can intitiated")
+		return c.String(http.StatusOK, api.WhoCan(verb, resource, group, namespace, l))
+	})
+
+	e.GET("/canI", func(c echo.Context) error {
+		kind := c.QueryParam("kind")
+		subject := c.QueryParam("subject")
+		verb := c.QueryParam("verb")
+		resource := c.QueryParam("resource")
+		group := c.QueryParam("group")
+		namespace := c.QueryParam("namespace")
+		name := c.QueryParam("name")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Access check intitiated")
+		return c.String(http.StatusOK, api.CanI(kind, subject, verb, resource, group, namespace, name, l))
+	})
+
+	e.GET("/get/:kind", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		name := c.QueryParam("name")
+		output := c.QueryParam("output")
+		clean := c.QueryParam("clean") == "True" || c.QueryParam("clean") == "true"
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get " + c.Param("kind") + " intitiated")
+		return c.String(http.StatusOK, api.GetObject(c.Param("kind"), namespace, name, output, clean, l))
+	})
+
+	e.GET("/crds", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get CustomResourceDefinitions intitiated")
+		return c.String(http.StatusOK, api.CRDs(l))
+	})
+
+	e.GET("/customResources", func(c echo.Context) error {
+		crd := c.QueryParam("crd")
+		version := c.QueryParam("version")
+		namespace := c.QueryParam("namespace")
+		allNamespaces := c.QueryParam("allNamespaces") == "True" || c.QueryParam("allNamespaces") == "true"
+		wide := c.QueryParam("wide") == "True" || c.QueryParam("wide") == "true"
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get CustomResources intitiated")
+		return c.String(http.StatusOK, api.CustomResources(crd, version, namespace, allNamespaces, wide, l))
+	})
+
+	// Generic routes for any kind the cluster serves, use "core" as the group of the core API
+	e.GET("/resources/:group/:version/:resource", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		allNamespaces := c.QueryParam("allNamespaces") == "True" || c.QueryParam("allNamespaces") == "true"
+		labelSelector := c.QueryParam("labelSelector")
+		fieldSelector := c.QueryParam("fieldSelector")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("List " + c.Param("resource") + " intitiated")
+		return c.String(http.StatusOK, api.ListResources(c.Param("group"), c.Param("version"), c.Param("resource"), namespace, allNamespaces, labelSelector, fieldSelector, l))
+	})
+
+	e.GET("/resources/:group/:version/:resource/:name", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get " + c.Param("resource") + " intitiated")
+		return c.String(http.StatusOK, api.GetResource(c.Param("group"), c.Param("version"), c.Param("resource"), namespace, c.Param("name"), l))
+	})
+
+	e.DELETE("/resources/:group/:version/:resource/:name", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Delete " + c.Param("resource") + " intitiated")
+		return c.String(http.StatusOK, api.DeleteResource(c.Param("group"), c.Param("version"), c.Param("resource"), namespace, c.Param("name"), l))
+	})
+
+	e.PATCH("/resources/:group/:version/:resource/:name", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		request := patchRequest(c)
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Patch " + c.Param("resource") + " intitiated")
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.PatchResource(c.Param("group"), c.Param("version"), c.Param("resource"), namespace, c.Param("name"), request, body, l))
+	})
+
+	e.PATCH("/patch/:kind", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		name := c.QueryParam("name")
+		request := patchRequest(c)
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Patch " + c.Param("kind") + " intitiated")
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.PatchObject(c.Param("kind"), namespace, name, request, body, l))
+	})
+
 	e.GET("/podLogs", func(c echo.Context) error {
 		namespace := c.QueryParam("namespace")
 		pod := c.QueryParam("pod")
@@ -199,7 +681,10 @@ func main() {
 		repo := c.QueryParam("repo")
 		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
 		l.Info("Adding Helm Install intitiated")
-		return c.String(http.StatusOK, install.InstallChart(namespace, chartName, name, repo, l))
+		op := operations.Submit("helmInstall", l, func(ctx context.Context, l *logrus.Entry) string {
+			return install.InstallChart(ctx, namespace, chartName, name, repo, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
 	})
 
 	e.POST("/createNamespace", func(c echo.Context) error {
@@ -209,11 +694,227 @@ func main() {
 		return c.String(http.StatusOK, api.CreateNamespace(namespace, l))
 	})
 
+	e.POST("/scaleStatefulSet", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		statefulSet := c.FormValue("statefulSet")
+		replicas := c.FormValue("replicas")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Scale StatefulSet intitiated")
+		return c.String(http.StatusOK, api.ScaleWorkload(context.Background(), "StatefulSet", namespace, statefulSet, replicas, "", false, "", l))
+	})
+
+	e.POST("/triggerCronJob", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		cronJob := c.FormValue("cronJob")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Trigger CronJob intitiated")
+		return c.String(http.StatusOK, api.TriggerCronJob(namespace, cronJob, l))
+	})
+
+	e.POST("/suspendCronJob", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		cronJob := c.FormValue("cronJob")
+		suspend := c.FormValue("suspend") != "False" && c.FormValue("suspend") != "false"
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Suspend CronJob intitiated")
+		return c.String(http.StatusOK, api.SuspendCronJob(namespace, cronJob, suspend, l))
+	})
+
+	e.POST("/expandPVC", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		pvc := c.FormValue("pvc")
+		size := c.FormValue("size")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Expand PersistentVolumeClaim intitiated")
+		return c.String(http.StatusOK, api.ExpandPersistentVolumeClaim(namespace, pvc, size, l))
+	})
+
+	e.POST("/cordonNode", func(c echo.Context) error {
+		node := c.FormValue("node")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Cordon Node intitiated")
+		return c.String(http.StatusOK, api.CordonNode(node, true, l))
+	})
+
+	e.POST("/uncordonNode", func(c echo.Context) error {
+		node := c.FormValue("node")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Uncordon Node intitiated")
+		return c.String(http.StatusOK, api.CordonNode(node, false, l))
+	})
+
+	e.POST("/scale", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		kind := c.FormValue("kind")
+		name := c.FormValue("name")
+		replicas := c.FormValue("replicas")
+		currentReplicas := c.FormValue("currentReplicas")
+		wait := c.FormValue("wait") == "True" || c.FormValue("wait") == "true"
+		timeout := c.FormValue("timeout")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Scale " + kind + " intitiated")
+		if !wait {
+			return c.String(http.StatusOK, api.ScaleWorkload(context.Background(), kind, namespace, name, replicas, currentReplicas, false, timeout, l))
+		}
+		op := operations.Submit("scale", l, func(ctx context.Context, l *logrus.Entry) string {
+			return api.ScaleWorkload(ctx, kind, namespace, name, replicas, currentReplicas, true, timeout, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
+	})
+
+	e.POST("/restartDeployment", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		deployment := c.FormValue("deployment")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Restart Deployment intitiated")
+		return c.String(http.StatusOK, api.RestartDeployment(context.Background(), namespace, deployment, l))
+	})
+
+	e.POST("/pauseDeployment", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		deployment := c.FormValue("deployment")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Pause Deployment intitiated")
+		return c.String(http.StatusOK, api.PauseDeployment(context.Background(), namespace, deployment, true, l))
+	})
+
+	e.POST("/resumeDeployment", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		deployment := c.FormValue("deployment")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Resume Deployment intitiated")
+		return c.String(http.StatusOK, api.PauseDeployment(context.Background(), namespace, deployment, false, l))
+	})
+
+	e.POST("/undoDeployment", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		deployment := c.FormValue("deployment")
+		toRevision := c.FormValue("toRevision")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Undo Deployment intitiated")
+		return c.String(http.StatusOK, api.UndoDeployment(context.Background(), namespace, deployment, toRevision, l))
+	})
+
+	e.POST("/setImage", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		kind := c.QueryParam("kind")
+		name := c.QueryParam("name")
+		changeCause := c.QueryParam("changeCause")
+		wait := c.QueryParam("wait") == "True" || c.QueryParam("wait") == "true"
+		timeout := c.QueryParam("timeout")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Set image intitiated")
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if !wait {
+			return c.String(http.StatusOK, api.SetImage(context.Background(), kind, namespace, name, body, changeCause, false, timeout, l))
+		}
+		op := operations.Submit("setImage", l, func(ctx context.Context, l *logrus.Entry) string {
+			return api.SetImage(ctx, kind, namespace, name, body, changeCause, true, timeout, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
+	})
+
+	e.POST("/createConfigMap", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Create ConfigMap intitiated")
+		request, err := keyValueRequest(c)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.CreateConfigMap(c.FormValue("namespace"), c.FormValue("name"), request, l))
+	})
+
+	e.POST("/updateConfigMap", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Update ConfigMap intitiated")
+		request, err := keyValueRequest(c)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.UpdateConfigMap(c.FormValue("namespace"), c.FormValue("name"), request, l))
+	})
+
+	e.POST("/createSecret", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Create Secret intitiated")
+		request, err := keyValueRequest(c)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if err := dockerConfigRequest(c, &request); err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.CreateSecret(c.FormValue("namespace"), c.FormValue("name"), c.FormValue("type"), request, l))
+	})
+
+	e.POST("/updateSecret", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Update Secret intitiated")
+		request, err := keyValueRequest(c)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		if err := dockerConfigRequest(c, &request); err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.UpdateSecret(c.FormValue("namespace"), c.FormValue("name"), request, l))
+	})
+
+	e.POST("/drainNode", func(c echo.Context) error {
+		node := c.FormValue("node")
+		gracePeriod := c.FormValue("gracePeriod")
+		timeout := c.FormValue("timeout")
+		force := c.FormValue("force") == "True" || c.FormValue("force") == "true"
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Drain Node intitiated")
+		op := operations.Submit("drainNode", l, func(ctx context.Context, l *logrus.Entry) string {
+			return api.DrainNode(ctx, node, gracePeriod, timeout, force, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
+	})
+
+	e.POST("/createHPA", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Create HorizontalPodAutoscaler intitiated")
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.CreateHPA(namespace, body, l))
+	})
+
+	e.PUT("/updateHPA", func(c echo.Context) error {
+		namespace := c.QueryParam("namespace")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Update HorizontalPodAutoscaler intitiated")
+		body, err := ioutil.ReadAll(c.Request().Body)
+		if err != nil {
+			l.Error(err.Error())
+			return c.String(http.StatusBadRequest, err.Error())
+		}
+		return c.String(http.StatusOK, api.UpdateHPA(namespace, body, l))
+	})
+
 	e.POST("/applyFile", func(c echo.Context) error {
 		filepath := c.FormValue("filepath")
 		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
 		l.Info("Intiating File appliying")
-		return c.String(http.StatusOK, apply.Main(filepath, l))
+		op := operations.Submit("applyFile", l, func(ctx context.Context, l *logrus.Entry) string {
+			return apply.Main(ctx, filepath, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
 	})
 
 	e.DELETE("/deleteHelm", func(c echo.Context) error {
@@ -279,6 +980,31 @@ func main() {
 		return c.String(http.StatusOK, api.DeleteDaemonSet(namespace, daemonSet, l))
 	})
 
+	e.DELETE("/deleteStatefulSet", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		statefulSet := c.FormValue("statefulSet")
+		retainPVCs := c.FormValue("retainPVCs") != "False" && c.FormValue("retainPVCs") != "false"
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Delete StatefulSet intitiated")
+		return c.String(http.StatusOK, api.DeleteStatefulSet(namespace, statefulSet, retainPVCs, l))
+	})
+
+	e.DELETE("/deletePVC", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		pvc := c.FormValue("pvc")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Delete PersistentVolumeClaim intitiated")
+		return c.String(http.StatusOK, api.DeletePersistentVolumeClaim(namespace, pvc, l))
+	})
+
+	e.DELETE("/deleteHPA", func(c echo.Context) error {
+		namespace := c.FormValue("namespace")
+		hpa := c.FormValue("hpa")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Delete HorizontalPodAutoscaler intitiated")
+		return c.String(http.StatusOK, api.DeleteHPA(namespace, hpa, l))
+	})
+
 	e.DELETE("/deletePod", func(c echo.Context) error {
 		namespace := c.FormValue("namespace")
 		pod := c.FormValue("pod")
@@ -295,9 +1021,103 @@ func main() {
 
 	e.DELETE("/deleteAll", func(c echo.Context) error {
 		namespace := c.FormValue("namespace")
+		retainPVCs := c.FormValue("retainPVCs") != "False" && c.FormValue("retainPVCs") != "false"
 		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
 		l.Info("Delete All intitiated")
-		return c.String(http.StatusOK, api.DeleteAll(namespace, l))
+		op := operations.Submit("deleteAll", l, func(ctx context.Context, l *logrus.Entry) string {
+			return api.DeleteAll(ctx, namespace, retainPVCs, l)
+		})
+		return c.String(http.StatusAccepted, operations.Get(op.ID, l))
+	})
+
+	// Routes for the long running operations started by the routes above
+	e.GET("/operations", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Operations intitiated")
+		return c.String(http.StatusOK, operations.List(l))
+	})
+
+	e.GET("/operations/history", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Operation History intitiated")
+		return c.String(http.StatusOK, operations.History(l))
+	})
+
+	e.GET("/operations/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Operation intitiated")
+		return c.String(http.StatusOK, operations.Get(id, l))
+	})
+
+	e.DELETE("/operations/:id", func(c echo.Context) error {
+		id := c.Param("id")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Cancel Operation intitiated")
+		return c.String(http.StatusOK, operations.Cancel(id, l))
+	})
+
+	// Routes for what kube-ez remembers in its local store
+	e.GET("/clusters", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Clusters intitiated")
+		return c.String(http.StatusOK, store.ListClusters(l))
+	})
+
+	e.POST("/registerCluster", func(c echo.Context) error {
+		name := c.FormValue("name")
+		server := c.FormValue("server")
+		kubeconfig := c.FormValue("kubeconfig")
+		kubeContext := c.FormValue("context")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Register Cluster intitiated")
+		return c.String(http.StatusOK, store.RegisterCluster(name, server, kubeconfig, kubeContext, l))
+	})
+
+	e.DELETE("/deleteCluster", func(c echo.Context) error {
+		name := c.FormValue("name")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Delete Cluster intitiated")
+		return c.String(http.StatusOK, store.DeleteCluster(name, l))
+	})
+
+	e.GET("/admin/audit", func(c echo.Context) error {
+		limit := c.QueryParam("limit")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Audit Log intitiated")
+		return c.String(http.StatusOK, store.AuditLog(limit, l))
+	})
+
+	e.GET("/admin/settings", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Get Settings intitiated")
+		return c.String(http.StatusOK, store.ListSettings(l))
+	})
+
+	e.POST("/admin/settings", func(c echo.Context) error {
+		key := c.FormValue("key")
+		value := c.FormValue("value")
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Save Setting intitiated")
+		return c.String(http.StatusOK, store.SetSetting(key, value, l))
+	})
+
+	e.GET("/admin/backup", func(c echo.Context) error {
+		l := log.WithFields(logrus.Fields{"uuid": c.Get("uuid")})
+		l.Info("Store Backup intitiated")
+		if store.DB == nil {
+			return c.String(http.StatusServiceUnavailable, "Store is not available")
+		}
+		c.Response().Header().Set(echo.HeaderContentType, echo.MIMEOctetStream)
+		c.Response().Header().Set(echo.HeaderContentDisposition, "attachment; filename=kube-ez-"+time.Now().Format("20060102-150405")+".db")
+		c.Response().WriteHeader(http.StatusOK)
+		n, err := store.DB.Backup(c.Response())
+		if err != nil {
+			l.Error(err.Error())
+			return nil
+		}
+		l.Info("Store backup written: " + fmt.Sprint(n) + " bytes")
+		return nil
 	})
 
 	// Run Server
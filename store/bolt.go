@@ -0,0 +1,175 @@
+package store
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+const metaBucket = "meta"
+
+// migrations are run in order, the number of the last one applied is kept in the meta bucket.
+// Never edit or reorder one that has shipped, append a new one instead.
+var migrations = []func(tx *bolt.Tx) error{
+	// 1: buckets for operations, audit records, clusters and settings
+	func(tx *bolt.Tx) error {
+		for _, name := range []string{Operations, Audit, Clusters, Settings} {
+			if _, err := tx.CreateBucketIfNotExists([]byte(name)); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+}
+
+// Bolt is the Storage backed by a single BoltDB file
+type Bolt struct {
+	db *bolt.DB
+}
+
+// This function opens (or creates) the BoltDB file at path and brings its schema up to date
+func OpenBolt(path string) (*Bolt, error) {
+	db, err := bolt.Open(path, 0600, &bolt.Options{Timeout: 2 * time.Second})
+	if err != nil {
+		return nil, err
+	}
+	b := &Bolt{db: db}
+	if err := b.migrate(); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return b, nil
+}
+
+func (b *Bolt) migrate() error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		meta, err := tx.CreateBucketIfNotExists([]byte(metaBucket))
+		if err != nil {
+			return err
+		}
+		version := 0
+		if v := meta.Get([]byte("schemaVersion")); v != nil {
+			version, err = strconv.Atoi(string(v))
+			if err != nil {
+				return err
+			}
+		}
+		if version > len(migrations) {
+			return fmt.Errorf("store schema version %d is newer than this kube-ez (%d)", version, len(migrations))
+		}
+		for i := version; i < len(migrations); i++ {
+			if err := migrations[i](tx); err != nil {
+				return fmt.Errorf("store migration %d failed: %w", i+1, err)
+			}
+		}
+		return meta.Put([]byte("schemaVersion"), []byte(strconv.Itoa(len(migrations))))
+	})
+}
+
+func (b *Bolt) Put(bucket, key string, value interface{}) error {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return err
+	}
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(bucket))
+		if bk == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		return bk.Put([]byte(key), data)
+	})
+}
+
+func (b *Bolt) Get(bucket, key string, value interface{}) error {
+	return b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(bucket))
+		if bk == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		data := bk.Get([]byte(key))
+		if data == nil {
+			return ErrNotFound
+		}
+		return json.Unmarshal(data, value)
+	})
+}
+
+func (b *Bolt) List(bucket string) ([]json.RawMessage, error) {
+	var values []json.RawMessage
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(bucket))
+		if bk == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		return bk.ForEach(func(k, v []byte) error {
+			// Values are only valid inside the transaction, so they are copied out
+			values = append(values, append(json.RawMessage(nil), v...))
+			return nil
+		})
+	})
+	return values, err
+}
+
+func (b *Bolt) Latest(bucket string, limit int) ([]json.RawMessage, error) {
+	var values []json.RawMessage
+	err := b.db.View(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(bucket))
+		if bk == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		c := bk.Cursor()
+		for k, v := c.Last(); k != nil && len(values) < limit; k, v = c.Prev() {
+			values = append(values, append(json.RawMessage(nil), v...))
+		}
+		return nil
+	})
+	return values, err
+}
+
+func (b *Bolt) DeleteBefore(bucket, key string) (int, error) {
+	n := 0
+	err := b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(bucket))
+		if bk == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		c := bk.Cursor()
+		// Deleting through the cursor moves it onto the next key
+		for k, _ := c.First(); k != nil && string(k) < key; k, _ = c.First() {
+			if err := c.Delete(); err != nil {
+				return err
+			}
+			n++
+		}
+		return nil
+	})
+	return n, err
+}
+
+func (b *Bolt) Delete(bucket, key string) error {
+	return b.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket([]byte(bucket))
+		if bk == nil {
+			return fmt.Errorf("bucket %s does not exist", bucket)
+		}
+		return bk.Delete([]byte(key))
+	})
+}
+
+func (b *Bolt) Backup(w io.Writer) (int64, error) {
+	var n int64
+	err := b.db.View(func(tx *bolt.Tx) error {
+		var err error
+		n, err = tx.WriteTo(w)
+		return err
+	})
+	return n, err
+}
+
+func (b *Bolt) Close() error {
+	return b.db.Close()
+}
@@ -0,0 +1,142 @@
+package store
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	bolt "go.etcd.io/bbolt"
+)
+
+func openTestBolt(t *testing.T) (*Bolt, string) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "kube-ez.db")
+	b, err := OpenBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { b.Close() })
+	return b, path
+}
+
+func TestMigrations(t *testing.T) {
+	b, path := openTestBolt(t)
+	err := b.db.View(func(tx *bolt.Tx) error {
+		for _, name := range []string{Operations, Audit, Clusters, Settings} {
+			if tx.Bucket([]byte(name)) == nil {
+				t.Errorf("bucket %s was not created", name)
+			}
+		}
+		if v := string(tx.Bucket([]byte(metaBucket)).Get([]byte("schemaVersion"))); v != strconv.Itoa(len(migrations)) {
+			t.Errorf("schemaVersion = %s, want %d", v, len(migrations))
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopening runs no migration again and keeps the data
+	if err := b.Put(Settings, "key", Setting{Key: "key", Value: "value"}); err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	b, err = OpenBolt(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var setting Setting
+	if err := b.Get(Settings, "key", &setting); err != nil || setting.Value != "value" {
+		t.Errorf("after reopening got %+v, %v", setting, err)
+	}
+
+	// A database written by a newer kube-ez is refused
+	err = b.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket([]byte(metaBucket)).Put([]byte("schemaVersion"), []byte(strconv.Itoa(len(migrations)+1)))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Close()
+	if b, err = OpenBolt(path); err == nil {
+		b.Close()
+		t.Error("opening a newer schema did not fail")
+	}
+}
+
+func TestBoltMissingBucket(t *testing.T) {
+	b, _ := openTestBolt(t)
+	if err := b.Put("missing", "key", 1); err == nil {
+		t.Error("Put into a missing bucket did not fail")
+	}
+	var v int
+	if err := b.Get(Settings, "missing", &v); err != ErrNotFound {
+		t.Errorf("Get of a missing key = %v, want ErrNotFound", err)
+	}
+}
+
+func TestLatest(t *testing.T) {
+	b, _ := openTestBolt(t)
+	for i := 1; i <= 5; i++ {
+		if err := b.Put(Audit, "k"+strconv.Itoa(i), i); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tests := []struct {
+		limit int
+		want  []int
+	}{
+		{2, []int{5, 4}},
+		{5, []int{5, 4, 3, 2, 1}},
+		{10, []int{5, 4, 3, 2, 1}},
+		{0, nil},
+	}
+	for _, tt := range tests {
+		values, err := b.Latest(Audit, tt.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var got []int
+		for _, value := range values {
+			var n int
+			if err := json.Unmarshal(value, &n); err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, n)
+		}
+		if len(got) != len(tt.want) {
+			t.Errorf("Latest(%d) = %v, want %v", tt.limit, got, tt.want)
+			continue
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Errorf("Latest(%d) = %v, want %v", tt.limit, got, tt.want)
+				break
+			}
+		}
+	}
+}
+
+func TestDeleteBefore(t *testing.T) {
+	b, _ := openTestBolt(t)
+	for _, key := range []string{"a", "b", "c", "d"} {
+		if err := b.Put(Audit, key, key); err != nil {
+			t.Fatal(err)
+		}
+	}
+	n, err := b.DeleteBefore(Audit, "c")
+	if err != nil || n != 2 {
+		t.Fatalf("DeleteBefore = %d, %v, want 2", n, err)
+	}
+	values, err := b.List(Audit)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(values) != 2 || string(values[0]) != `"c"` || string(values[1]) != `"d"` {
+		t.Errorf("left %s, want c and d", values)
+	}
+	if n, err = b.DeleteBefore(Audit, "a"); err != nil || n != 0 {
+		t.Errorf("DeleteBefore with nothing older = %d, %v, want 0", n, err)
+	}
+}
@@ -0,0 +1,298 @@
+package store
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/distribution/distribution/v3/uuid"
+	"github.com/sirupsen/logrus"
+)
+
+// These are the buckets kube-ez keeps its state in
+const (
+	Operations = "operations"
+	Audit      = "audit"
+	Clusters   = "clusters"
+	Settings   = "settings"
+)
+
+// ErrNotFound is returned by Get when the key is not in the bucket
+var ErrNotFound = errors.New("not found")
+
+// Storage is everything kube-ez needs from its database. Values are stored as JSON.
+type Storage interface {
+	Put(bucket, key string, value interface{}) error
+	Get(bucket, key string, value interface{}) error
+	// List returns the values of a bucket in key order
+	List(bucket string) ([]json.RawMessage, error)
+	// Latest returns up to limit values of a bucket, last key first
+	Latest(bucket string, limit int) ([]json.RawMessage, error)
+	// DeleteBefore deletes the keys of a bucket that sort before key and returns how many went
+	DeleteBefore(bucket, key string) (int, error)
+	Delete(bucket, key string) error
+	// Backup writes a consistent copy of the whole database to w
+	Backup(w io.Writer) (int64, error)
+	Close() error
+}
+
+// setting a Global variable for the store so that it can be reused throughout the code, nil when it could not be opened
+var DB Storage
+
+// These are all the Structs that are kept in the store
+type AuditRecord struct {
+	Time      string
+	RequestID string
+	Method    string
+	URI       string
+	RemoteIP  string
+	Status    int
+}
+
+type Cluster struct {
+	Name       string
+	Server     string
+	Kubeconfig string
+	Context    string
+	CreatedAt  string
+}
+
+// ClusterInfo is what the clusters route shows of a registered cluster, the kubeconfig is never given back
+type ClusterInfo struct {
+	Name      string
+	Server    string
+	Context   string
+	CreatedAt string
+}
+
+type Setting struct {
+	Key   string
+	Value string
+}
+
+// This function opens the database at KUBE_EZ_DB (default kube-ez.db) and runs its migrations.
+// kube-ez keeps working without it, only nothing is remembered across restarts.
+func Main() {
+	path := os.Getenv("KUBE_EZ_DB")
+	if path == "" {
+		path = "kube-ez.db"
+	}
+	db, err := OpenBolt(path)
+	if err != nil {
+		logrus.Error("Unable to open the store at " + path + ". Error: " + err.Error())
+		return
+	}
+	logrus.Info("Store opened at " + path)
+	DB = db
+}
+
+const auditKeyTime = "20060102T150405.000000000"
+
+// This function records a request that changed something, it is called by the audit middleware
+func AddAudit(record AuditRecord, log *logrus.Entry) {
+	if DB == nil {
+		return
+	}
+	// Keys start with the time so that the bucket stays in chronological order
+	key := time.Now().UTC().Format(auditKeyTime) + "-" + uuid.Generate().String()[:8]
+	if err := DB.Put(Audit, key, record); err != nil {
+		log.Error(err.Error())
+	}
+}
+
+// This function returns the latest audit records, newest first
+func AuditLog(limit string, log *logrus.Entry) string {
+	if DB == nil {
+		return "Store is not available"
+	}
+	max := 100
+	if limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			log.Error("Invalid limit: " + limit)
+			return "Invalid limit: " + limit
+		}
+		max = n
+	}
+	auditInfo, err := DB.Latest(Audit, max)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	audit_json, err := json.Marshal(auditInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(audit_json)
+}
+
+// This function drops the audit records older than before, it is called by the operations janitor
+func TrimAudit(before time.Time, log *logrus.Entry) {
+	if DB == nil {
+		return
+	}
+	n, err := DB.DeleteBefore(Audit, before.UTC().Format(auditKeyTime))
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	if n > 0 {
+		log.Info("Audit records dropped: " + strconv.Itoa(n))
+	}
+}
+
+// This function drops the operations that finished before before, it is called by the operations janitor.
+// Operations that have not finished are kept whatever their age.
+func TrimOperations(before time.Time, log *logrus.Entry) {
+	if DB == nil {
+		return
+	}
+	values, err := DB.List(Operations)
+	if err != nil {
+		log.Error(err.Error())
+		return
+	}
+	n := 0
+	for _, value := range values {
+		var op struct {
+			ID         string
+			FinishedAt string
+		}
+		if err := json.Unmarshal(value, &op); err != nil || op.FinishedAt == "" {
+			continue
+		}
+		finished, err := time.Parse(time.RFC3339, op.FinishedAt)
+		if err != nil || !finished.Before(before) {
+			continue
+		}
+		if err := DB.Delete(Operations, op.ID); err != nil {
+			log.Error(err.Error())
+			return
+		}
+		n++
+	}
+	if n > 0 {
+		log.Info("Stored operations dropped: " + strconv.Itoa(n))
+	}
+}
+
+// This function saves a cluster so that it is remembered across restarts
+func RegisterCluster(name, server, kubeconfig, context string, log *logrus.Entry) string {
+	if DB == nil {
+		return "Store is not available"
+	}
+	if name == "" || server == "" {
+		log.Error("Cluster name and server are required")
+		return "Cluster name and server are required"
+	}
+	cluster := Cluster{
+		Name:       name,
+		Server:     server,
+		Kubeconfig: kubeconfig,
+		Context:    context,
+		CreatedAt:  time.Now().Format(time.RFC3339),
+	}
+	if err := DB.Put(Clusters, name, cluster); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Cluster: " + name + " Registered!")
+	return "Cluster: " + name + " Registered!"
+}
+
+// This function returns all the registered clusters, without their kubeconfig
+func ListClusters(log *logrus.Entry) string {
+	if DB == nil {
+		return "Store is not available"
+	}
+	values, err := DB.List(Clusters)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	clusterInfo := []ClusterInfo{}
+	for _, value := range values {
+		var cluster Cluster
+		if err := json.Unmarshal(value, &cluster); err != nil {
+			log.Error(err.Error())
+			return err.Error()
+		}
+		clusterInfo = append(clusterInfo, ClusterInfo{Name: cluster.Name, Server: cluster.Server, Context: cluster.Context, CreatedAt: cluster.CreatedAt})
+	}
+	clusters_json, err := json.Marshal(clusterInfo)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(clusters_json)
+}
+
+// This function forgets a registered cluster
+func DeleteCluster(name string, log *logrus.Entry) string {
+	if DB == nil {
+		return "Store is not available"
+	}
+	if err := DB.Delete(Clusters, name); err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Cluster: " + name + " Deleted!")
+	return "Cluster: " + name + " Deleted!"
+}
+
+// This function returns a setting, or fallback when it was never saved
+func GetSetting(key, fallback string) string {
+	if DB == nil {
+		return fallback
+	}
+	var setting Setting
+	if err := DB.Get(Settings, key, &setting); err != nil {
+		return fallback
+	}
+	return setting.Value
+}
+
+// This function saves a setting, an empty value removes it
+func SetSetting(key, value string, log *logrus.Entry) string {
+	if DB == nil {
+		return "Store is not available"
+	}
+	if key == "" {
+		log.Error("Setting key is required")
+		return "Setting key is required"
+	}
+	var err error
+	if value == "" {
+		err = DB.Delete(Settings, key)
+	} else {
+		err = DB.Put(Settings, key, Setting{Key: key, Value: value})
+	}
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	log.Info("Setting: " + key + " Saved!")
+	return "Setting: " + key + " Saved!"
+}
+
+// This function returns all the saved settings
+func ListSettings(log *logrus.Entry) string {
+	if DB == nil {
+		return "Store is not available"
+	}
+	values, err := DB.List(Settings)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	settings_json, err := json.Marshal(values)
+	if err != nil {
+		log.Error(err.Error())
+		return err.Error()
+	}
+	return string(settings_json)
+}
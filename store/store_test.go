@@ -0,0 +1,129 @@
+package store
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+)
+
+func useTestDB(t *testing.T) *logrus.Entry {
+	t.Helper()
+	b, _ := openTestBolt(t)
+	DB = b
+	t.Cleanup(func() { DB = nil })
+	return logrus.NewEntry(logrus.New())
+}
+
+func putAudit(t *testing.T, at time.Time, uri string) {
+	t.Helper()
+	key := at.UTC().Format(auditKeyTime) + "-" + uri
+	if err := DB.Put(Audit, key, AuditRecord{Time: at.Format(time.RFC3339), URI: uri}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func auditURIs(t *testing.T, limit string, log *logrus.Entry) []string {
+	t.Helper()
+	var records []AuditRecord
+	if err := json.Unmarshal([]byte(AuditLog(limit, log)), &records); err != nil {
+		t.Fatal(err)
+	}
+	var uris []string
+	for _, record := range records {
+		uris = append(uris, record.URI)
+	}
+	return uris
+}
+
+func TestAuditTrimAndPaging(t *testing.T) {
+	log := useTestDB(t)
+	now := time.Now()
+	// Written out of order, the keys keep them in time order
+	putAudit(t, now.Add(-1*time.Hour), "/recent")
+	putAudit(t, now.Add(-30*24*time.Hour), "/old")
+	putAudit(t, now, "/now")
+	putAudit(t, now.Add(-8*24*time.Hour), "/week")
+
+	if got := strings.Join(auditURIs(t, "", log), ","); got != "/now,/recent,/week,/old" {
+		t.Errorf("AuditLog = %s", got)
+	}
+	if got := strings.Join(auditURIs(t, "2", log), ","); got != "/now,/recent" {
+		t.Errorf("AuditLog limit 2 = %s", got)
+	}
+	for _, limit := range []string{"0", "-1", "ten"} {
+		if got := AuditLog(limit, log); got != "Invalid limit: "+limit {
+			t.Errorf("AuditLog(%q) = %s", limit, got)
+		}
+	}
+
+	TrimAudit(now.Add(-7*24*time.Hour), log)
+	if got := strings.Join(auditURIs(t, "", log), ","); got != "/now,/recent" {
+		t.Errorf("after TrimAudit = %s", got)
+	}
+}
+
+func TestTrimOperations(t *testing.T) {
+	log := useTestDB(t)
+	now := time.Now()
+	operations := []struct {
+		ID         string
+		FinishedAt string
+	}{
+		{"old", now.Add(-48 * time.Hour).Format(time.RFC3339)},
+		{"new", now.Add(-time.Hour).Format(time.RFC3339)},
+		{"running", ""},
+	}
+	for _, op := range operations {
+		if err := DB.Put(Operations, op.ID, op); err != nil {
+			t.Fatal(err)
+		}
+	}
+	TrimOperations(now.Add(-24*time.Hour), log)
+	var op struct{ ID string }
+	if err := DB.Get(Operations, "old", &op); err != ErrNotFound {
+		t.Errorf("old operation was kept: %v", err)
+	}
+	for _, id := range []string{"new", "running"} {
+		if err := DB.Get(Operations, id, &op); err != nil {
+			t.Errorf("operation %s was dropped: %v", id, err)
+		}
+	}
+}
+
+func TestSettings(t *testing.T) {
+	if got := GetSetting("auditRetention", "168h"); got != "168h" {
+		t.Errorf("without a store GetSetting = %s, want the fallback", got)
+	}
+	log := useTestDB(t)
+	if got := SetSetting("", "1h", log); got != "Setting key is required" {
+		t.Errorf("SetSetting without key = %s", got)
+	}
+	SetSetting("auditRetention", "24h", log)
+	if got := GetSetting("auditRetention", "168h"); got != "24h" {
+		t.Errorf("GetSetting = %s, want 24h", got)
+	}
+	if got := ListSettings(log); got != `[{"Key":"auditRetention","Value":"24h"}]` {
+		t.Errorf("ListSettings = %s", got)
+	}
+	// An empty value removes the setting and the fallback is back
+	SetSetting("auditRetention", "", log)
+	if got := GetSetting("auditRetention", "168h"); got != "168h" {
+		t.Errorf("after removing GetSetting = %s, want 168h", got)
+	}
+}
+
+func TestListClustersHidesKubeconfig(t *testing.T) {
+	log := useTestDB(t)
+	RegisterCluster("prod", "https://prod:6443", "apiVersion: v1\nusers: [secret]", "admin", log)
+	got := ListClusters(log)
+	if strings.Contains(got, "Kubeconfig") || strings.Contains(got, "secret") {
+		t.Errorf("ListClusters gives back the kubeconfig: %s", got)
+	}
+	var clusters []ClusterInfo
+	if err := json.Unmarshal([]byte(got), &clusters); err != nil || len(clusters) != 1 || clusters[0].Server != "https://prod:6443" {
+		t.Errorf("ListClusters = %s, %v", got, err)
+	}
+}